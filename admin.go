@@ -0,0 +1,20 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/auth"
+)
+
+// isAdmin checks the ADMIN_KEY api key, admin endpoints are disabled when it isn't set
+func (cfg *apiConfig) isAdmin(r *http.Request) bool {
+	if cfg.adminKey == "" {
+		return false
+	}
+	requestApiKey, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(requestApiKey), []byte(cfg.adminKey)) == 1
+}
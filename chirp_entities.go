@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/chirptext"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// saveChirpEntities replaces the hashtags and mentions stored for a chirp with the ones in its body
+// the body must already have gone through filterWords, which censors tags and mentions the way
+// chirptext finds them, so censored words can't become tags
+func saveChirpEntities(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	if err := clearChirpEntities(ctx, q, chirpID); err != nil {
+		return err
+	}
+
+	if tags := chirptext.Hashtags(body); len(tags) > 0 {
+		err := q.CreateChirpTags(ctx, database.CreateChirpTagsParams{ChirpID: chirpID, Tags: tags})
+		if err != nil {
+			return err
+		}
+	}
+
+	// handles that don't belong to anyone are ignored by the query
+	if handles := chirptext.Mentions(body); len(handles) > 0 {
+		err := q.CreateChirpMentions(ctx, database.CreateChirpMentionsParams{ChirpID: chirpID, Handles: handles})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func clearChirpEntities(ctx context.Context, q *database.Queries, chirpID uuid.UUID) error {
+	if err := q.DeleteChirpTags(ctx, chirpID); err != nil {
+		return err
+	}
+	return q.DeleteChirpMentions(ctx, chirpID)
+}
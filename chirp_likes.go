@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// optionalUserID returns the id of the user making the request
+// anonymous requests and requests with an invalid token get an invalid NullUUID instead of an error
+func (cfg *apiConfig) optionalUserID(r *http.Request) uuid.NullUUID {
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// hydrateChirps fills in everything a chirp payload needs beyond its own row:
+// the embedded originals of reposts, and the like counts and media of both
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, viewerID uuid.NullUUID, chirps []*Chirp) error {
+	originals, err := cfg.attachOriginals(ctx, chirps)
+	if err != nil {
+		return err
+	}
+	allChirps := append(chirps, originals...)
+	if err := cfg.attachLikes(ctx, viewerID, allChirps); err != nil {
+		return err
+	}
+	return cfg.attachMedia(ctx, allChirps)
+}
+
+// attachLikes fills like_count and liked_by_me on every chirp with a single query
+func (cfg *apiConfig) attachLikes(ctx context.Context, viewerID uuid.NullUUID, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	stats, err := cfg.databaseQueries.GetLikeStats(ctx, database.GetLikeStatsParams{
+		ViewerID: viewerID,
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	// chirps without likes have no row in the result
+	statsByChirp := make(map[uuid.UUID]database.GetLikeStatsRow, len(stats))
+	for _, stat := range stats {
+		statsByChirp[stat.ChirpID] = stat
+	}
+	for _, chirp := range chirps {
+		stat := statsByChirp[chirp.ID]
+		chirp.LikeCount = int(stat.LikeCount)
+		chirp.LikedByMe = stat.LikedByMe
+	}
+	return nil
+}
+
+// chirpPointers lets handlers pass a page of chirps to attachLikes
+func chirpPointers(chirps []Chirp) []*Chirp {
+	pointers := make([]*Chirp, len(chirps))
+	for i := range chirps {
+		pointers[i] = &chirps[i]
+	}
+	return pointers
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+const maxMediaSize = 5 << 20
+
+// allowedMediaTypes are the sniffed content types accepted by POST /api/media
+var allowedMediaTypes = map[string]bool{
+	"image/gif":  true,
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+type Media struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// mediaURL is content addressed, so the file behind it never changes
+func mediaURL(sha256 string) string {
+	return "/media/" + sha256
+}
+
+func mediaFromDatabase(dbMedia database.MediaFile) Media {
+	return Media{
+		ID:          dbMedia.ID,
+		URL:         mediaURL(dbMedia.Sha256),
+		ContentType: dbMedia.ContentType,
+		SizeBytes:   dbMedia.SizeBytes,
+		CreatedAt:   dbMedia.CreatedAt,
+	}
+}
+
+// attachMedia fills the media of every chirp with a single query
+func (cfg *apiConfig) attachMedia(ctx context.Context, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	rows, err := cfg.databaseQueries.GetChirpAttachments(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	// rows come ordered by position inside each chirp
+	mediaByChirp := map[uuid.UUID][]Media{}
+	for _, row := range rows {
+		mediaByChirp[row.ChirpID] = append(mediaByChirp[row.ChirpID], mediaFromDatabase(database.MediaFile{
+			ID:          row.ID,
+			UserID:      row.UserID,
+			Sha256:      row.Sha256,
+			ContentType: row.ContentType,
+			SizeBytes:   row.SizeBytes,
+			CreatedAt:   row.CreatedAt,
+		}))
+	}
+	for _, chirp := range chirps {
+		chirp.Media = mediaByChirp[chirp.ID]
+	}
+	return nil
+}
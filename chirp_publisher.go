@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// publishBatchSize is how many due chirps one instance claims at a time
+const publishBatchSize = 100
+
+// runChirpPublisher publishes scheduled chirps once their publish_at has passed
+// rows are claimed with FOR UPDATE SKIP LOCKED, so several chirpy instances can run it against the same database
+func (cfg *apiConfig) runChirpPublisher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cfg.publishDueChirps(ctx)
+		}
+	}
+}
+
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) {
+	// keep going while full batches come back, there may be more due chirps
+	for {
+		published, err := cfg.publishChirpBatch(ctx)
+		if err != nil {
+			log.Printf("error publishing scheduled chirps: %v", err)
+			return
+		}
+		if published > 0 {
+			log.Printf("published %d scheduled chirps", published)
+		}
+		if published < publishBatchSize {
+			return
+		}
+	}
+}
+
+// publishChirpBatch publishes one batch of due chirps and queues their chirp.created events
+// in the same transaction, so subscribers hear about a chirp once it's public and only then
+func (cfg *apiConfig) publishChirpBatch(ctx context.Context) (int, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
+
+	published, err := qtx.PublishDueChirps(ctx, publishBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, dbChirp := range published {
+		if err := enqueueWebhookEvent(ctx, qtx, dbChirp.UserID, webhookChirpCreated, chirpFromDatabase(dbChirp)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(published), nil
+}
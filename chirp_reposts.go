@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// isPlainRechirp reports whether the chirp is a repost without its own body
+func isPlainRechirp(dbChirp database.Chirp) bool {
+	return dbChirp.RepostOf.Valid && dbChirp.Body == "" && !dbChirp.DeletedAt.Valid
+}
+
+// contentChirpID returns the chirp that holds the content
+// plain rechirps have none of their own, so replies, reposts and likes go to the original
+func contentChirpID(dbChirp database.Chirp) uuid.UUID {
+	if isPlainRechirp(dbChirp) {
+		return dbChirp.RepostOf.UUID
+	}
+	return dbChirp.ID
+}
+
+// attachOriginals embeds the reposted chirp in every repost with a single query
+// and returns the embedded chirps so callers can keep filling them in
+func (cfg *apiConfig) attachOriginals(ctx context.Context, chirps []*Chirp) ([]*Chirp, error) {
+	originalIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RepostOf != nil {
+			originalIDs = append(originalIDs, *chirp.RepostOf)
+		}
+	}
+	if len(originalIDs) == 0 {
+		return nil, nil
+	}
+
+	dbOriginals, err := cfg.databaseQueries.GetChirpsByIDs(ctx, originalIDs)
+	if err != nil {
+		return nil, err
+	}
+	originalsByID := make(map[uuid.UUID]database.Chirp, len(dbOriginals))
+	for _, dbOriginal := range dbOriginals {
+		originalsByID[dbOriginal.ID] = dbOriginal
+	}
+
+	// deleted originals are kept as tombstones, so they're still embedded with an empty body
+	originals := []*Chirp{}
+	for _, chirp := range chirps {
+		if chirp.RepostOf == nil {
+			continue
+		}
+		dbOriginal, ok := originalsByID[*chirp.RepostOf]
+		if !ok {
+			continue
+		}
+		original := chirpFromDatabase(dbOriginal)
+		chirp.Original = &original
+		originals = append(originals, chirp.Original)
+	}
+	return originals, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/mailer"
+)
+
+const emailVerificationDuration = 24 * time.Hour
+
+// parseEmail only accepts a bare address, without a display name
+func parseEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", errors.New("Email is invalid")
+	}
+	return email, nil
+}
+
+// startEmailVerification replaces the user's pending verification and returns the token to email,
+// the address is only set on the account once the token is confirmed
+func startEmailVerification(ctx context.Context, qtx *database.Queries, userID uuid.UUID, email string) (string, error) {
+	if err := qtx.ExpireEmailVerifications(ctx, userID); err != nil {
+		return "", err
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	err = qtx.CreateEmailVerification(ctx, database.CreateEmailVerificationParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(emailVerificationDuration),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sendEmailVerification emails the verification link in the background
+func (cfg *apiConfig) sendEmailVerification(email string, token string) {
+	message := mailer.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email",
+		Body: fmt.Sprintf("Open this link to verify your email:\n\n%s/api/verify-email?token=%s\n\n"+
+			"It expires in %s. If you didn't sign up for Chirpy, you can ignore this email.\n",
+			cfg.appURL, url.QueryEscape(token), emailVerificationDuration),
+	}
+	go func() {
+		if err := cfg.mailer.Send(context.Background(), message); err != nil {
+			log.Printf("error sending verification email: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/entitlements"
+)
+
+// userPlan returns the plan the user is entitled to, users without chirpy red are on the free plan
+func (cfg *apiConfig) userPlan(ctx context.Context, user database.User) (string, error) {
+	// is_chirpy_red already says if the subscription is still running
+	if !user.IsChirpyRed.Bool {
+		return entitlements.FreePlan, nil
+	}
+	subscription, err := cfg.databaseQueries.GetSubscription(ctx, user.ID)
+	if err == sql.ErrNoRows {
+		return planChirpyRed, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return subscription.Plan, nil
+}
+
+// userLimits returns what the user's plan allows
+func (cfg *apiConfig) userLimits(ctx context.Context, user database.User) (entitlements.Limits, error) {
+	plan, err := cfg.userPlan(ctx, user)
+	if err != nil {
+		return entitlements.Limits{}, err
+	}
+	return cfg.plans.For(plan), nil
+}
+
+// chirpRateWindow is the window MaxChirpsPerHour applies to
+const chirpRateWindow = time.Hour
+
+// chirpRateLimited reports whether the user already posted as many chirps as their plan allows in the
+// last hour. posts are counted in chirp_posts, which keeps them after the chirp is deleted
+func (cfg *apiConfig) chirpRateLimited(ctx context.Context, userID uuid.UUID, limits entitlements.Limits) (bool, error) {
+	since := time.Now().Add(-chirpRateWindow)
+	// older posts don't count anymore, so only the last hour is kept
+	err := cfg.databaseQueries.DeleteChirpPostsBefore(ctx, database.DeleteChirpPostsBeforeParams{
+		UserID:    userID,
+		CreatedAt: since,
+	})
+	if err != nil {
+		return false, err
+	}
+	recentPosts, err := cfg.databaseQueries.CountChirpPostsSince(ctx, database.CountChirpPostsSinceParams{
+		UserID:    userID,
+		CreatedAt: since,
+	})
+	if err != nil {
+		return false, err
+	}
+	return recentPosts >= int64(limits.MaxChirpsPerHour), nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/entitlements"
+)
+
+func TestChirpRateLimited(t *testing.T) {
+	limits := entitlements.Limits{MaxChirpsPerHour: 3}
+
+	tests := []struct {
+		name         string
+		posts        int
+		deleteChirps bool
+		want         bool
+	}{
+		{name: "Under the limit", posts: 2, want: false},
+		{name: "At the limit", posts: 3, want: true},
+		// deleting the chirps doesn't give the posts back
+		{name: "Deleted chirps still count", posts: 3, deleteChirps: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			chirpColumns := []string{"id", "created_at", "updated_at", "body", "user_id", "search_vector", "in_reply_to", "deleted_at", "repost_of", "publish_at"}
+
+			// the chirps and chirp_posts tables, kept in memory
+			var mu sync.Mutex
+			chirps := map[string][]driver.Value{}
+			posts := []time.Time{}
+
+			fake := newFakeDB(map[string]fakeQuery{
+				"RecordChirpPost": func(args []driver.Value) fakeResult {
+					mu.Lock()
+					defer mu.Unlock()
+					posts = append(posts, time.Now())
+					return fakeResult{}
+				},
+				"CountChirpPostsSince": func(args []driver.Value) fakeResult {
+					mu.Lock()
+					defer mu.Unlock()
+					count := int64(0)
+					for _, postedAt := range posts {
+						if postedAt.After(args[1].(time.Time)) {
+							count++
+						}
+					}
+					return fakeResult{columns: []string{"count"}, rows: [][]driver.Value{{count}}}
+				},
+				"DeleteChirpPostsBefore": func(args []driver.Value) fakeResult {
+					mu.Lock()
+					defer mu.Unlock()
+					kept := []time.Time{}
+					for _, postedAt := range posts {
+						if postedAt.After(args[1].(time.Time)) {
+							kept = append(kept, postedAt)
+						}
+					}
+					posts = kept
+					return fakeResult{}
+				},
+				"DeleteChirp": func(args []driver.Value) fakeResult {
+					mu.Lock()
+					defer mu.Unlock()
+					id := args[0].(string)
+					row, ok := chirps[id]
+					if !ok {
+						return fakeResult{columns: chirpColumns}
+					}
+					delete(chirps, id)
+					return fakeResult{columns: chirpColumns, rows: [][]driver.Value{row}}
+				},
+			})
+			db := openFakeDB(fake)
+			defer db.Close()
+			cfg := &apiConfig{db: db, databaseQueries: database.New(db)}
+			ctx := context.Background()
+
+			// what handleCreateChirps records for every chirp
+			chirpIDs := []uuid.UUID{}
+			for range tt.posts {
+				if err := cfg.databaseQueries.RecordChirpPost(ctx, userID); err != nil {
+					t.Fatalf("error recording post: %v", err)
+				}
+				chirpID := uuid.New()
+				now := time.Now()
+				mu.Lock()
+				chirps[chirpID.String()] = []driver.Value{chirpID.String(), now, now, "chirp", userID.String(), nil, nil, nil, nil, nil}
+				mu.Unlock()
+				chirpIDs = append(chirpIDs, chirpID)
+			}
+
+			if tt.deleteChirps {
+				for _, chirpID := range chirpIDs {
+					if _, err := cfg.databaseQueries.DeleteChirp(ctx, chirpID); err != nil {
+						t.Fatalf("error deleting chirp: %v", err)
+					}
+				}
+				if len(chirps) != 0 {
+					t.Fatalf("%d chirps weren't deleted", len(chirps))
+				}
+			}
+
+			got, err := cfg.chirpRateLimited(ctx, userID, limits)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("chirpRateLimited() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeResult is what a fake query answers with, no rows makes :one queries return sql.ErrNoRows
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// fakeQuery answers one sqlc query, it gets the query's arguments
+type fakeQuery func(args []driver.Value) fakeResult
+
+// fakeDB is a database/sql connector for handler tests. queries are matched by their sqlc name,
+// every query that runs without an answer fails the test through the error it returns
+type fakeDB struct {
+	mu      sync.Mutex
+	queries map[string]fakeQuery
+	calls   []string
+}
+
+func newFakeDB(queries map[string]fakeQuery) *fakeDB {
+	return &fakeDB{queries: queries}
+}
+
+// called reports whether the query ran
+func (db *fakeDB) called(name string) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, call := range db.calls {
+		if call == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *fakeDB) run(query string, namedArgs []driver.NamedValue) (fakeResult, error) {
+	// sqlc starts every query with "-- name: QueryName :kind"
+	fields := strings.Fields(query)
+	if len(fields) < 3 || fields[1] != "name:" {
+		return fakeResult{}, fmt.Errorf("query without a sqlc name: %q", query)
+	}
+	name := fields[2]
+
+	db.mu.Lock()
+	db.calls = append(db.calls, name)
+	answer, ok := db.queries[name]
+	db.mu.Unlock()
+	if !ok {
+		return fakeResult{}, fmt.Errorf("unexpected query %s", name)
+	}
+
+	args := make([]driver.Value, len(namedArgs))
+	for i, arg := range namedArgs {
+		args[i] = arg.Value
+	}
+	return answer(args), nil
+}
+
+func (db *fakeDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{db: db}, nil
+}
+
+func (db *fakeDB) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("use sql.OpenDB with a fakeDB")
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements aren't supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{result: result}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(result.rows)), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.result.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+// openFakeDB returns a database handle that answers with the fake queries
+func openFakeDB(db *fakeDB) *sql.DB {
+	return sql.OpenDB(db)
+}
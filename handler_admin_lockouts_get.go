@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LoginLockout is an email or ip address that can't log in until LockedUntil
+type LoginLockout struct {
+	Email        string    `json:"email,omitempty"`
+	IPAddress    string    `json:"ip_address,omitempty"`
+	Failures     int       `json:"failures"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+	LockedUntil  time.Time `json:"locked_until"`
+}
+
+func (cfg *apiConfig) handleAdminLockoutsGet(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	dbLockouts, err := cfg.databaseQueries.GetActiveLoginLockouts(r.Context())
+	if err != nil {
+		log.Printf("error getting login lockouts: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	lockouts := []LoginLockout{}
+	for _, dbLockout := range dbLockouts {
+		loginLockout := LoginLockout{
+			Failures:     int(dbLockout.Failures),
+			LastFailedAt: dbLockout.LastFailedAt,
+			LockedUntil:  dbLockout.LockedUntil.Time,
+		}
+		if email, isEmail := strings.CutPrefix(dbLockout.Key, "email:"); isEmail {
+			loginLockout.Email = email
+		} else {
+			loginLockout.IPAddress = strings.TrimPrefix(dbLockout.Key, "ip:")
+		}
+		lockouts = append(lockouts, loginLockout)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(lockouts); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
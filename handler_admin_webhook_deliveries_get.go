@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// WebhookDeliveriesPage is the response body of the delivery log listing
+type WebhookDeliveriesPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// handleAdminWebhookDeliveriesGet lists the delivery log, newest first. it can be filtered by
+// direction, provider, event_type and status (succeeded or failed)
+func (cfg *apiConfig) handleAdminWebhookDeliveriesGet(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	params := database.GetWebhookDeliveriesParams{
+		Provider:  nullString(query.Get("provider")),
+		EventType: nullString(query.Get("event_type")),
+	}
+
+	switch direction := query.Get("direction"); direction {
+	case "":
+	case webhookInbound, webhookOutbound:
+		params.Direction = nullString(direction)
+	default:
+		log.Printf("invalid direction: %s", direction)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "direction must be inbound or outbound"})
+		return
+	}
+
+	switch status := query.Get("status"); status {
+	case "":
+	case "succeeded":
+		params.Succeeded = sql.NullBool{Bool: true, Valid: true}
+	case "failed":
+		params.Succeeded = sql.NullBool{Bool: false, Valid: true}
+	default:
+		log.Printf("invalid status: %s", status)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "status must be succeeded or failed"})
+		return
+	}
+
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		log.Printf("invalid limit: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+
+	if cursorString := query.Get("cursor"); cursorString != "" {
+		cursor, err := decodeCursor(cursorString)
+		if err != nil {
+			log.Printf("invalid cursor: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: "Invalid cursor"})
+			return
+		}
+		params.CursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	// one extra row tells if there's a next page
+	params.Limit = int32(limit + 1)
+	dbDeliveries, err := cfg.databaseQueries.GetWebhookDeliveries(r.Context(), params)
+	if err != nil {
+		log.Printf("error getting webhook deliveries: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	page := WebhookDeliveriesPage{Deliveries: []WebhookDelivery{}}
+	if len(dbDeliveries) > limit {
+		dbDeliveries = dbDeliveries[:limit]
+		last := dbDeliveries[limit-1]
+		page.NextCursor = encodeCursor(chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, dbDelivery := range dbDeliveries {
+		page.Deliveries = append(page.Deliveries, webhookDeliveryFromDatabase(dbDelivery))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// handleAdminWebhookDeliveryReplay runs a logged delivery again through the code that handled it
+// the first time and logs the new attempt with replay_of pointing at the original
+func (cfg *apiConfig) handleAdminWebhookDeliveryReplay(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// get the delivery id from the URL path
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		log.Println("Error parsing UUID string from URL:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	original, err := cfg.databaseQueries.GetWebhookDelivery(r.Context(), deliveryID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting webhook delivery: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	replayOf := uuid.NullUUID{UUID: original.ID, Valid: true}
+
+	var replay database.WebhookDelivery
+	switch {
+	case original.Direction == webhookInbound && original.Provider.String == polkaProvider:
+		// anyone can post to the webhook url, only bodies polka signed are trusted
+		// and unsigned ones are only logged truncated
+		if !original.SignatureVerified {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(errorResponse{Error: "Deliveries without a valid signature can't be replayed"})
+			return
+		}
+
+		// an event that was already applied is acknowledged without applying it twice
+		outcome := cfg.processPolkaEvent(r.Context(), []byte(original.RequestBody))
+
+		headers := http.Header{}
+		if err := json.Unmarshal([]byte(original.RequestHeaders), &headers); err != nil {
+			log.Printf("error decoding logged headers: %v", err)
+		}
+		replay, err = cfg.logPolkaDelivery(r.Context(), headers, []byte(original.RequestBody), true, outcome, replayOf)
+
+	case original.Direction == webhookOutbound:
+		// the outbox entry is deleted with its subscription
+		if !original.OutboxID.Valid {
+			w.WriteHeader(http.StatusGone)
+			json.NewEncoder(w).Encode(errorResponse{Error: "The webhook subscription was deleted"})
+			return
+		}
+		outboxDelivery, err := cfg.databaseQueries.GetWebhookOutboxDelivery(r.Context(), original.OutboxID.UUID)
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusGone)
+			json.NewEncoder(w).Encode(errorResponse{Error: "The webhook subscription was deleted"})
+			return
+		}
+		if err != nil {
+			log.Printf("error getting outbox delivery: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// both rows have the same columns, so the outbox entry is sent like a claimed one
+		delivery := database.ClaimWebhookDeliveriesRow(outboxDelivery)
+		headers, status, deliveryErr := cfg.sendWebhook(r.Context(), delivery)
+		if deliveryErr == nil {
+			// a dead or pending delivery that went through on replay shouldn't be sent again
+			if err := cfg.databaseQueries.MarkWebhookDelivered(r.Context(), delivery.ID); err != nil {
+				log.Printf("error marking webhook %v delivered: %v", delivery.ID, err)
+			}
+		}
+		replay, err = cfg.logOutboundDelivery(r.Context(), delivery, headers, status, deliveryErr, replayOf)
+
+	default:
+		log.Printf("can't replay %s delivery from %q", original.Direction, original.Provider.String)
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(errorResponse{Error: "This delivery can't be replayed"})
+		return
+	}
+	if err != nil {
+		log.Printf("error logging replayed delivery: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(webhookDeliveryFromDatabase(replay)); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
@@ -33,12 +33,11 @@ func (cfg *apiConfig) handleChirpGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		UserID:    dbChirp.UserID,
-		Body:      dbChirp.Body,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
+	chirp := chirpFromDatabase(dbChirp)
+	if err := cfg.hydrateChirps(r.Context(), cfg.optionalUserID(r), []*Chirp{&chirp}); err != nil {
+		log.Printf("error hydrating chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
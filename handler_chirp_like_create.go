@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleChirpLikeCreate(w http.ResponseWriter, r *http.Request) {
+
+	// get chirp id from the URL path
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Println("Error parsing UUID string from URL:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// tombstones can't be liked, likes on a plain rechirp go to the original
+	chirp, err := cfg.databaseQueries.GetChirp(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && chirp.DeletedAt.Valid) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// liking a chirp twice is a no-op
+	err = cfg.databaseQueries.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: contentChirpID(chirp),
+	})
+	if err != nil {
+		log.Printf("error liking chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
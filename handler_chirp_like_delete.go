@@ -0,0 +1,67 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleChirpLikeDelete(w http.ResponseWriter, r *http.Request) {
+
+	// get chirp id from the URL path
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Println("Error parsing UUID string from URL:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	chirp, err := cfg.databaseQueries.GetChirp(r.Context(), chirpID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// likes on a plain rechirp were stored on the original
+	deleted, err := cfg.databaseQueries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: contentChirpID(chirp),
+	})
+	if err != nil {
+		log.Printf("error unliking chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// the chirp wasn't liked by the user
+	if deleted == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+func (cfg *apiConfig) handleChirpRevisionsGet(w http.ResponseWriter, r *http.Request) {
+
+	// get chirp id from the URL path
+	strID := r.PathValue("chirpID")
+	chirpID, err := uuid.Parse(strID)
+	if err != nil {
+		log.Println("Error parsing UUID string from URL:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// revisions of a chirp that doesn't exist are a 404, not an empty list
+	_, err = cfg.databaseQueries.GetChirp(r.Context(), chirpID)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	dbRevisions, err := cfg.databaseQueries.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("error getting chirp revisions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	revisions := []ChirpRevision{}
+	for _, dbRevision := range dbRevisions {
+		revisions = append(revisions, ChirpRevision{
+			ID:         dbRevision.ID,
+			ChirpID:    dbRevision.ChirpID,
+			Body:       dbRevision.Body,
+			CreatedAt:  dbRevision.CreatedAt,
+			ReplacedAt: dbRevision.ReplacedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		log.Printf("error encoding response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
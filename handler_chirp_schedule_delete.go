@@ -0,0 +1,56 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// handleChirpScheduleDelete cancels a scheduled chirp that hasn't been published yet
+func (cfg *apiConfig) handleChirpScheduleDelete(w http.ResponseWriter, r *http.Request) {
+
+	// get chirp id from the URL path
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		log.Println("Error parsing UUID string from URL:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// the query only matches chirps that are still pending, so it can't race with the publisher
+	_, err = cfg.databaseQueries.CancelScheduledChirp(r.Context(), database.CancelScheduledChirpParams{
+		ID:     chirpID,
+		UserID: userID,
+	})
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("error cancelling scheduled chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
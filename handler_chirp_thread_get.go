@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// ChirpThreadNode is a chirp with its replies nested below it
+type ChirpThreadNode struct {
+	Chirp
+	Replies []*ChirpThreadNode `json:"replies"`
+}
+
+// ChirpThread is the conversation around a chirp
+// ancestors start at the root of the conversation and end at the chirp's parent
+type ChirpThread struct {
+	Ancestors []Chirp          `json:"ancestors"`
+	Chirp     *ChirpThreadNode `json:"chirp"`
+}
+
+func (cfg *apiConfig) handleChirpThreadGet(w http.ResponseWriter, r *http.Request) {
+
+	// get chirp id from the URL path
+	strID := r.PathValue("chirpID")
+	chirpID, err := uuid.Parse(strID)
+	if err != nil {
+		log.Println("Error parsing UUID string from URL:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// rows come ordered by depth: ancestors have negative depths, the chirp itself is 0, replies are positive
+	rows, err := cfg.databaseQueries.GetChirpThread(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("error getting chirp thread: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(rows) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	thread := ChirpThread{Ancestors: []Chirp{}}
+	nodes := map[uuid.UUID]*ChirpThreadNode{}
+	for _, row := range rows {
+		chirp := Chirp{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			Body:      row.Body,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			InReplyTo: nullUUIDPtr(row.InReplyTo),
+			DeletedAt: nullTimePtr(row.DeletedAt),
+			RepostOf:  nullUUIDPtr(row.RepostOf),
+		}
+		if row.Depth < 0 {
+			thread.Ancestors = append(thread.Ancestors, chirp)
+			continue
+		}
+		node := &ChirpThreadNode{Chirp: chirp, Replies: []*ChirpThreadNode{}}
+		nodes[row.ID] = node
+		if row.Depth == 0 {
+			thread.Chirp = node
+			continue
+		}
+		// parents always come before their replies because of the depth ordering
+		parent := nodes[row.InReplyTo.UUID]
+		parent.Replies = append(parent.Replies, node)
+	}
+
+	// every chirp in the thread, so likes are fetched with one query
+	allChirps := chirpPointers(thread.Ancestors)
+	for _, node := range nodes {
+		allChirps = append(allChirps, &node.Chirp)
+	}
+	if err := cfg.hydrateChirps(r.Context(), cfg.optionalUserID(r), allChirps); err != nil {
+		log.Printf("error hydrating chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(thread); err != nil {
+		log.Printf("error encoding response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -1,24 +1,71 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/lib/pq"
+	"github.com/troclaux/chirpy/internal/chirptext"
 	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/entitlements"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+	// only set on tombstones, deleted chirps that are kept because replies or quotes point to them
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	RepostOf  *uuid.UUID `json:"repost_of,omitempty"`
+	// the reposted chirp, a tombstone if it was deleted
+	Original *Chirp `json:"original,omitempty"`
+	// only read from requests, responses have the media itself
+	MediaIDs []uuid.UUID `json:"media_ids,omitempty"`
+	Media    []Media     `json:"media,omitempty"`
+	// only set while the chirp is waiting to be published
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	LikeCount int        `json:"like_count"`
+	// only computed when the request has a valid bearer token
+	LikedByMe bool `json:"liked_by_me"`
+}
+
+// chirpFromDatabase converts a chirp row into the chirp returned by the API
+func chirpFromDatabase(dbChirp database.Chirp) Chirp {
+	return Chirp{
+		ID:        dbChirp.ID,
+		UserID:    dbChirp.UserID,
+		Body:      dbChirp.Body,
+		CreatedAt: dbChirp.CreatedAt,
+		UpdatedAt: dbChirp.UpdatedAt,
+		InReplyTo: nullUUIDPtr(dbChirp.InReplyTo),
+		DeletedAt: nullTimePtr(dbChirp.DeletedAt),
+		RepostOf:  nullUUIDPtr(dbChirp.RepostOf),
+		PublishAt: nullTimePtr(dbChirp.PublishAt),
+	}
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
 }
 
 var badWords = map[string]int{
@@ -34,7 +81,19 @@ func filterWords(originalString string, badWords map[string]int) string {
 			words[i] = "****"
 		}
 	}
-	return strings.Join(words, " ")
+	// a censored word can't sneak through as a hashtag or a mention, even next to punctuation
+	return chirptext.CensorEntities(strings.Join(words, " "), func(word string) bool {
+		_, exists := badWords[word]
+		return exists
+	})
+}
+
+// validateChirpBody checks the chirp length against the poster's plan and returns the body with bad words censored
+func validateChirpBody(body string, limits entitlements.Limits) (string, error) {
+	if len(body) > limits.MaxChirpLength {
+		return "", fmt.Errorf("Chirp is too long, the limit is %d characters", limits.MaxChirpLength)
+	}
+	return filterWords(body, badWords), nil
 }
 
 func (cfg *apiConfig) handleCreateChirps(w http.ResponseWriter, r *http.Request) {
@@ -48,52 +107,233 @@ func (cfg *apiConfig) handleCreateChirps(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// check if request has jwt
-	jwtString, err := auth.GetBearerToken(r.Header)
+	// accepts a jwt or a personal access token with the chirps:write scope
+	userID, err := cfg.authenticate(r, scopeChirpsWrite)
+	if err == errMissingScope {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
-		log.Printf("error getting bearer token: %v", err)
+		log.Printf("error authenticating request: %v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	// get the server's signing key from .env
-	jwtSigningKey := os.Getenv("SIGNING_KEY")
+	// only accounts with a verified email can post
+	poster, err := cfg.databaseQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !poster.EmailVerifiedAt.Valid {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Verify your email before posting"})
+		return
+	}
 
-	// validate jwt string
-	userID, err := auth.ValidateJWT(jwtString, jwtSigningKey)
+	limits, err := cfg.userLimits(r.Context(), poster)
 	if err != nil {
-		log.Printf("error validating jwt: %v", err)
-		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("error getting user limits: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if len(post.Body) > 140 {
+	// posts are counted in the database so the limit holds across instances
+	rateLimited, err := cfg.chirpRateLimited(r.Context(), userID, limits)
+	if err != nil {
+		log.Printf("error counting recent chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if rateLimited {
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(errorResponse{Error: fmt.Sprintf("You can post at most %d chirps per hour", limits.MaxChirpsPerHour)})
+		return
+	}
+
+	filteredText, err := validateChirpBody(post.Body, limits)
+	if err != nil {
 		// write the status code 400 in the response
 		w.WriteHeader(http.StatusBadRequest)
 		// write the error message in the response
 		// errorResponse struct is used to format the error message in JSON
-		json.NewEncoder(w).Encode(errorResponse{Error: "Chirp is too long"})
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
 		return
 	}
 
-	filteredText := filterWords(post.Body, badWords)
+	// replies can only point to chirps that still exist
+	inReplyTo := uuid.NullUUID{}
+	if post.InReplyTo != nil {
+		parent, err := cfg.databaseQueries.GetChirp(r.Context(), *post.InReplyTo)
+		if err == sql.ErrNoRows || (err == nil && parent.DeletedAt.Valid) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: "Chirp being replied to doesn't exist"})
+			return
+		}
+		if err != nil {
+			log.Printf("error getting parent chirp: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: contentChirpID(parent), Valid: true}
+	}
+
+	// a repost without a body is a plain rechirp, with a body it's a quote
+	repostOf := uuid.NullUUID{}
+	if post.RepostOf != nil {
+		original, err := cfg.databaseQueries.GetChirp(r.Context(), *post.RepostOf)
+		if err == sql.ErrNoRows || (err == nil && original.DeletedAt.Valid) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: "Chirp being reposted doesn't exist"})
+			return
+		}
+		if err != nil {
+			log.Printf("error getting reposted chirp: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if filteredText == "" && inReplyTo.Valid {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: "A rechirp can't be a reply"})
+			return
+		}
+		repostOf = uuid.NullUUID{UUID: contentChirpID(original), Valid: true}
+	}
+
+	// scheduled chirps stay pending until the publisher picks them up
+	publishAt := sql.NullTime{}
+	if post.PublishAt != nil {
+		if !limits.ScheduledChirps {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(errorResponse{Error: "Your plan doesn't include scheduled chirps"})
+			return
+		}
+		if !post.PublishAt.After(time.Now()) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: "publish_at must be in the future"})
+			return
+		}
+		// the column has no time zone, so like time.Now() everywhere else it's stored in local time
+		// and compared with NOW() by the publisher
+		publishAt = sql.NullTime{Time: post.PublishAt.Local(), Valid: true}
+	}
+
+	// attached media must have been uploaded by the poster
+	mediaIDs := []uuid.UUID{}
+	seenMedia := map[uuid.UUID]bool{}
+	for _, mediaID := range post.MediaIDs {
+		if !seenMedia[mediaID] {
+			seenMedia[mediaID] = true
+			mediaIDs = append(mediaIDs, mediaID)
+		}
+	}
+	if len(mediaIDs) > limits.MaxChirpMedia {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: fmt.Sprintf("A chirp can have at most %d media", limits.MaxChirpMedia)})
+		return
+	}
+	if len(mediaIDs) > 0 && filteredText == "" && repostOf.Valid {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "A rechirp can't have media"})
+		return
+	}
+	if len(mediaIDs) > 0 {
+		ownedMedia, err := cfg.databaseQueries.GetOwnedMediaFiles(r.Context(), database.GetOwnedMediaFilesParams{
+			Ids:    mediaIDs,
+			UserID: userID,
+		})
+		if err != nil {
+			log.Printf("error getting media: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(ownedMedia) != len(mediaIDs) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: "Media doesn't exist"})
+			return
+		}
+	}
 
 	// get user_id from the request and create a new uuid
-	params := database.CreateChirpParams{Body: filteredText, UserID: userID}
+	params := database.CreateChirpParams{
+		Body:      filteredText,
+		UserID:    userID,
+		InReplyTo: inReplyTo,
+		RepostOf:  repostOf,
+		PublishAt: publishAt,
+	}
+
+	// the chirp and its hashtags and mentions are stored together
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
 
-	newChirp, err := cfg.databaseQueries.CreateChirp(r.Context(), params)
+	newChirp, err := qtx.CreateChirp(r.Context(), params)
+	// the partial unique index only allows one plain rechirp of a chirp per user
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Chirp was already rechirped"})
+		return
+	}
 	if err != nil {
 		log.Printf("error creating chirp: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	chirp := Chirp{
-		ID:        newChirp.ID,
-		UserID:    newChirp.UserID,
-		Body:      newChirp.Body,
-		CreatedAt: newChirp.CreatedAt,
-		UpdatedAt: newChirp.UpdatedAt,
+	// recorded with the chirp, deleting the chirp later doesn't give the post back
+	if err := qtx.RecordChirpPost(r.Context(), userID); err != nil {
+		log.Printf("error recording chirp post: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(mediaIDs) > 0 {
+		err := qtx.AttachMediaToChirp(r.Context(), database.AttachMediaToChirpParams{
+			ChirpID:  newChirp.ID,
+			MediaIds: mediaIDs,
+		})
+		if err != nil {
+			log.Printf("error attaching media: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := saveChirpEntities(r.Context(), qtx, newChirp.ID, newChirp.Body); err != nil {
+		log.Printf("error saving chirp hashtags and mentions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// scheduled chirps aren't public yet, the publisher sends chirp.created for them
+	if !publishAt.Valid {
+		if err := enqueueWebhookEvent(r.Context(), qtx, userID, webhookChirpCreated, chirpFromDatabase(newChirp)); err != nil {
+			log.Printf("error enqueueing webhook event: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chirp := chirpFromDatabase(newChirp)
+	if err := cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*Chirp{&chirp}); err != nil {
+		log.Printf("error hydrating chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -2,12 +2,11 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
 	"log"
 	"net/http"
-	"os"
 
 	"github.com/google/uuid"
-	"github.com/troclaux/chirpy/internal/auth"
 )
 
 func (cfg *apiConfig) handleChirpDelete(w http.ResponseWriter, r *http.Request) {
@@ -20,27 +19,31 @@ func (cfg *apiConfig) handleChirpDelete(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// check if request has authorization headers
-	jwtString, err := auth.GetBearerToken(r.Header)
+	// accepts a jwt or a personal access token with the chirps:delete scope
+	userID, err := cfg.authenticate(r, scopeChirpsDelete)
+	if err == errMissingScope {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
-		log.Printf("error getting bearer token: %v", err)
+		log.Printf("error authenticating request: %v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	// get the server's signing key from .env
-	jwtSigningKey := os.Getenv("SIGNING_KEY")
-
-	// validate jwt string
-	userID, err := auth.ValidateJWT(jwtString, jwtSigningKey)
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Printf("error validating jwt: %v", err)
-		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
 
-	chirp, err := cfg.databaseQueries.GetChirp(r.Context(), chirpID)
-	if err == sql.ErrNoRows {
+	// lock the chirp so no reply or quote can be added between the check below and the delete
+	chirp, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && chirp.DeletedAt.Valid) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
@@ -54,24 +57,67 @@ func (cfg *apiConfig) handleChirpDelete(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	deletedChirp, err := cfg.databaseQueries.DeleteChirp(r.Context(), chirpID)
-	// if chirp's not found in database
-	if err == sql.ErrNoRows {
-		log.Printf("couldn't delete chirp that matches both id and userID: %v", err)
-		w.WriteHeader(http.StatusNotFound)
+	// plain rechirps have no content of their own, they go away with the original
+	err = qtx.DeletePlainRechirps(r.Context(), uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		log.Printf("error deleting rechirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	if deletedChirp.UserID != userID {
-		log.Printf("chirp's userID is not jwt's userID: %v", err)
-		w.WriteHeader(http.StatusForbidden)
+
+	isReferenced, err := qtx.ChirpIsReferenced(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("error checking chirp references: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+
+	if isReferenced {
+		// keep a tombstone so replies still belong to the thread and quotes still embed something
+		// the old bodies, hashtags, mentions and media are removed along with the chirp's own body
+		if err := qtx.DeleteChirpRevisions(r.Context(), chirpID); err != nil {
+			log.Printf("error deleting chirp revisions: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := clearChirpEntities(r.Context(), qtx, chirpID); err != nil {
+			log.Printf("error deleting chirp hashtags and mentions: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := qtx.DeleteChirpAttachments(r.Context(), chirpID); err != nil {
+			log.Printf("error deleting chirp media: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, err = qtx.TombstoneChirp(r.Context(), chirpID)
+	} else {
+		_, err = qtx.DeleteChirp(r.Context(), chirpID)
+	}
 	if err != nil {
 		log.Printf("error deleting chirp: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	type deletedChirp struct {
+		ID uuid.UUID `json:"id"`
+	}
+	// subscribers never heard of a chirp that was still scheduled
+	if !chirp.PublishAt.Valid {
+		if err := enqueueWebhookEvent(r.Context(), qtx, userID, webhookChirpDeleted, deletedChirp{ID: chirpID}); err != nil {
+			log.Printf("error enqueueing webhook event: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing chirp deletion: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNoContent)
 
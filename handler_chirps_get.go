@@ -5,62 +5,99 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"sort"
 
 	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
 )
 
 func (cfg *apiConfig) handleChirpsGet(w http.ResponseWriter, r *http.Request) {
 
-	dbChirps, err := cfg.databaseQueries.GetChirps(r.Context())
-	if err == sql.ErrNoRows {
-		log.Printf("couldn't get chirps: %v", err)
-		w.WriteHeader(http.StatusNotFound)
+	authorIDString := r.URL.Query().Get("author_id")
+	sortOrder := r.URL.Query().Get("sort")
+	cursorString := r.URL.Query().Get("cursor")
+
+	if sortOrder != "" && sortOrder != "asc" && sortOrder != "desc" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "sort must be asc or desc"})
 		return
 	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
 	if err != nil {
-		log.Printf("error getting chirps: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
 		return
 	}
 
-	authorID := uuid.Nil
-	authorIDString := r.URL.Query().Get("author_id")
-	sortOrder := r.URL.Query().Get("sort")
-
+	authorID := uuid.NullUUID{}
 	if authorIDString != "" {
-		authorID, err = uuid.Parse(authorIDString)
+		authorID.UUID, err = uuid.Parse(authorIDString)
 		if err != nil {
 			log.Println("Error parsing UUID string from URL:", err)
+			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		authorID.Valid = true
 	}
 
-	// IMPORTANT: convert the dbChirps to a map of Chirps{}
-	chirps := []Chirp{}
-	for _, dbChirp := range dbChirps {
-		if authorID != dbChirp.UserID && authorID != uuid.Nil {
-			continue
+	cursorCreatedAt := sql.NullTime{}
+	cursorID := uuid.NullUUID{}
+	if cursorString != "" {
+		cursor, err := decodeCursor(cursorString)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+			return
 		}
-		chirps = append(chirps, Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			UserID:    dbChirp.UserID,
-			Body:      dbChirp.Body,
-		})
+		cursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		cursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
 	}
 
+	// fetch one extra row to find out if there's a next page
+	var dbChirps []database.Chirp
 	if sortOrder == "desc" {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
+		dbChirps, err = cfg.databaseQueries.GetChirpsPageDesc(r.Context(), database.GetChirpsPageDescParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           int32(limit + 1),
+		})
+	} else {
+		dbChirps, err = cfg.databaseQueries.GetChirpsPageAsc(r.Context(), database.GetChirpsPageAscParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           int32(limit + 1),
 		})
 	}
+	if err != nil {
+		log.Printf("error getting chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	page := ChirpsPage{Chirps: []Chirp{}}
+	if len(dbChirps) > limit {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = encodeCursor(chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	// IMPORTANT: convert the dbChirps to a slice of Chirps{}
+	for _, dbChirp := range dbChirps {
+		page.Chirps = append(page.Chirps, chirpFromDatabase(dbChirp))
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), cfg.optionalUserID(r), chirpPointers(page.Chirps)); err != nil {
+		log.Printf("error hydrating chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	if err := json.NewEncoder(w).Encode(chirps); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		log.Printf("error encoding response: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+)
+
+func (cfg *apiConfig) handleChirpsScheduledGet(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// pending chirps are only visible to their owner, soonest first
+	dbChirps, err := cfg.databaseQueries.GetScheduledChirps(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting scheduled chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chirps := []Chirp{}
+	for _, dbChirp := range dbChirps {
+		chirps = append(chirps, chirpFromDatabase(dbChirp))
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirpPointers(chirps)); err != nil {
+		log.Printf("error hydrating chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(chirps); err != nil {
+		log.Printf("error encoding response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
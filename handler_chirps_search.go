@@ -0,0 +1,104 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleChirpsSearch(w http.ResponseWriter, r *http.Request) {
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	authorIDString := r.URL.Query().Get("author_id")
+	cursorString := r.URL.Query().Get("cursor")
+
+	if query == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "q is required"})
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+
+	authorID := uuid.NullUUID{}
+	if authorIDString != "" {
+		authorID.UUID, err = uuid.Parse(authorIDString)
+		if err != nil {
+			log.Println("Error parsing UUID string from URL:", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		authorID.Valid = true
+	}
+
+	cursorRank := sql.NullFloat64{}
+	cursorID := uuid.NullUUID{}
+	if cursorString != "" {
+		cursor, err := decodeSearchCursor(cursorString)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+			return
+		}
+		cursorRank = sql.NullFloat64{Float64: float64(cursor.Rank), Valid: true}
+		cursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	// results are ranked by relevance, fetch one extra row to find out if there's a next page
+	results, err := cfg.databaseQueries.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:      query,
+		AuthorID:   authorID,
+		CursorRank: cursorRank,
+		CursorID:   cursorID,
+		Limit:      int32(limit + 1),
+	})
+	if err != nil {
+		log.Printf("error searching chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	page := ChirpsPage{Chirps: []Chirp{}}
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[len(results)-1]
+		page.NextCursor = encodeSearchCursor(searchCursor{Rank: last.Rank, ID: last.ID})
+	}
+
+	for _, result := range results {
+		page.Chirps = append(page.Chirps, Chirp{
+			ID:        result.ID,
+			CreatedAt: result.CreatedAt,
+			UpdatedAt: result.UpdatedAt,
+			UserID:    result.UserID,
+			Body:      result.Body,
+			InReplyTo: nullUUIDPtr(result.InReplyTo),
+			RepostOf:  nullUUIDPtr(result.RepostOf),
+		})
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), cfg.optionalUserID(r), chirpPointers(page.Chirps)); err != nil {
+		log.Printf("error hydrating chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		log.Printf("error encoding response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleChirpUpdate(w http.ResponseWriter, r *http.Request) {
+
+	// get chirp id from the URL path
+	strID := r.PathValue("chirpID")
+	chirpID, err := uuid.Parse(strID)
+	if err != nil {
+		log.Println("Error parsing UUID string from URL:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("error decoding chirp: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.databaseQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	limits, err := cfg.userLimits(r.Context(), user)
+	if err != nil {
+		log.Printf("error getting user limits: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if limits.EditWindow == 0 {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Your plan doesn't include editing chirps"})
+		return
+	}
+
+	// edits go through the same checks as new chirps
+	filteredText, err := validateChirpBody(params.Body, limits)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+
+	// the old body is saved as a revision in the same transaction as the update
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
+
+	// lock the chirp so concurrent edits don't lose revisions
+	chirp, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && chirp.DeletedAt.Valid) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if chirp.UserID != userID {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	// the window starts when the chirp is published, scheduled chirps can be edited until then
+	publishedAt := chirp.CreatedAt
+	if chirp.PublishAt.Valid && chirp.PublishAt.Time.After(publishedAt) {
+		publishedAt = chirp.PublishAt.Time
+	}
+	editWindow := time.Duration(limits.EditWindow)
+	if time.Since(publishedAt) > editWindow {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(errorResponse{Error: fmt.Sprintf("Chirps can only be edited for %v after they're published", editWindow)})
+		return
+	}
+	// an empty body would turn a quote into a plain rechirp
+	if isPlainRechirp(chirp) || (chirp.RepostOf.Valid && filteredText == "") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Rechirps can't be edited"})
+		return
+	}
+
+	_, err = qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+		ChirpID:   chirp.ID,
+		Body:      chirp.Body,
+		CreatedAt: chirp.UpdatedAt,
+	})
+	if err != nil {
+		log.Printf("error creating chirp revision: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	updatedChirp, err := qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		Body: filteredText,
+		ID:   chirp.ID,
+	})
+	if err != nil {
+		log.Printf("error updating chirp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := saveChirpEntities(r.Context(), qtx, updatedChirp.ID, updatedChirp.Body); err != nil {
+		log.Printf("error saving chirp hashtags and mentions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing chirp update: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := chirpFromDatabase(updatedChirp)
+	if err := cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*Chirp{&response}); err != nil {
+		log.Printf("error hydrating chirps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("error encoding response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/entitlements"
+)
+
+// handleEntitlementsGet tells clients the limits of the user's plan, like how long chirps can be
+func (cfg *apiConfig) handleEntitlementsGet(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.databaseQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	plan, err := cfg.userPlan(r.Context(), user)
+	if err != nil {
+		log.Printf("error getting user plan: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	type Entitlements struct {
+		Plan   string              `json:"plan"`
+		Limits entitlements.Limits `json:"limits"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(Entitlements{Plan: plan, Limits: cfg.plans.For(plan)}); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
@@ -1,68 +1,173 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/webhook"
 )
 
+const (
+	polkaProvider        = "polka"
+	polkaSignatureHeader = "X-Polka-Signature"
+	// deliveries signed longer ago than this are refused, so a captured request can't be replayed later
+	webhookSignatureTolerance = 5 * time.Minute
+	maxWebhookBodyBytes       = 1 << 20
+	// anyone can send unsigned requests, so only the start of their body is logged
+	maxUnsignedLoggedBodyBytes = 4 << 10
+)
+
+// polkaEvent is the body of polka's webhooks
+type polkaEvent struct {
+	// polka keeps the same id when it retries a delivery
+	ID    string                `json:"id"`
+	Event string                `json:"event"`
+	Data  SubscriptionEventData `json:"data"`
+}
+
+// webhookOutcome is what came of a webhook request, it's saved in the delivery log
+type webhookOutcome struct {
+	status    int
+	eventID   string
+	eventType string
+	err       error
+}
+
 func (cfg *apiConfig) handleEventWebhook(w http.ResponseWriter, r *http.Request) {
 
-	// check if request has correct api key
-	requestApiKey, err := auth.GetAPIKey(r.Header)
+	// the signature covers the raw body, so it's read before decoding
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
 	if err != nil {
-		log.Printf("error getting authorization header: %v", err)
-		w.WriteHeader(http.StatusUnauthorized)
-		return
-	}
-	if requestApiKey != cfg.polkaKey {
-		log.Printf("invalid polka key: %v", err)
-		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("error reading webhook body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	// decode request
-	decoder := json.NewDecoder(r.Body)
+	// check if request was signed with the polka key
+	err = webhook.Verify(cfg.polkaKey, r.Header.Get(polkaSignatureHeader), body, time.Now(), webhookSignatureTolerance)
+	signatureVerified := err == nil
+	var outcome webhookOutcome
+	if signatureVerified {
+		outcome = cfg.processPolkaEvent(r.Context(), body)
+	} else {
+		// a wrong POLKA_KEY or clock skew rejects every delivery, so rejections are logged too
+		log.Printf("invalid polka signature: %v", err)
+		outcome = webhookOutcome{status: http.StatusUnauthorized, err: err}
+		// the event is only read to make the log easier to search
+		event := polkaEvent{}
+		if json.Unmarshal(body, &event) == nil {
+			outcome.eventID = event.ID
+			outcome.eventType = event.Event
+		}
+		if len(body) > maxUnsignedLoggedBodyBytes {
+			body = body[:maxUnsignedLoggedBodyBytes]
+		}
+	}
 
-	type Webhook struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID uuid.UUID `json:"user_id"`
-		} `json:"data"`
+	if _, err := cfg.logPolkaDelivery(r.Context(), r.Header, body, signatureVerified, outcome, uuid.NullUUID{}); err != nil {
+		log.Printf("error logging polka delivery: %v", err)
 	}
 
-	webhook := Webhook{}
+	w.WriteHeader(outcome.status)
+}
+
+// processPolkaEvent applies a polka event whose signature was checked, it's also used to replay logged events
+func (cfg *apiConfig) processPolkaEvent(ctx context.Context, body []byte) webhookOutcome {
+	event := polkaEvent{}
 
 	// read decoded data and store it in empty struct
-	if err := decoder.Decode(&webhook); err != nil {
-		log.Printf("error decoding credentials: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	if err := json.Unmarshal(body, &event); err != nil {
+		log.Printf("error decoding webhook: %v", err)
+		return webhookOutcome{status: http.StatusBadRequest, err: err}
+	}
+	outcome := webhookOutcome{eventID: event.ID, eventType: event.Event}
+	if event.ID == "" {
+		log.Println("polka webhook without an event id")
+		outcome.status = http.StatusBadRequest
+		outcome.err = errors.New("event has no id")
+		return outcome
 	}
 
-	if webhook.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent)
-		return
+	if !subscriptionEvents[event.Event] {
+		outcome.status = http.StatusNoContent
+		return outcome
 	}
 
-	if webhook.Event == "user.upgraded" {
-		_, err := cfg.databaseQueries.UpgradeUser(r.Context(), webhook.Data.UserID)
-		if err == sql.ErrNoRows {
-			log.Printf("couldn't find user: %v", err)
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-		if err != nil {
-			log.Printf("error upgrading user to chirpy red: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNoContent)
-		return
+	// the event is recorded with its effect, so either both happen or a retry applies it again
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		outcome.status = http.StatusInternalServerError
+		outcome.err = err
+		return outcome
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
+
+	recorded, err := qtx.RecordWebhookEvent(ctx, database.RecordWebhookEventParams{
+		Provider:  polkaProvider,
+		EventID:   event.ID,
+		EventType: event.Event,
+	})
+	if err != nil {
+		log.Printf("error recording webhook event: %v", err)
+		outcome.status = http.StatusInternalServerError
+		outcome.err = err
+		return outcome
+	}
+	// already applied, the retry is acknowledged so polka stops sending it
+	if recorded == 0 {
+		log.Printf("polka event %s was already applied", event.ID)
+		outcome.status = http.StatusNoContent
+		return outcome
 	}
 
+	err = applySubscriptionEvent(ctx, qtx, event.Event, event.Data)
+	if err == errSubscriptionUserNotFound {
+		log.Printf("couldn't find user: %v", event.Data.UserID)
+		outcome.status = http.StatusNotFound
+		outcome.err = err
+		return outcome
+	}
+	if err != nil {
+		log.Printf("error applying subscription event: %v", err)
+		outcome.status = http.StatusInternalServerError
+		outcome.err = err
+		return outcome
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing webhook event: %v", err)
+		outcome.status = http.StatusInternalServerError
+		outcome.err = err
+		return outcome
+	}
+
+	outcome.status = http.StatusNoContent
+	return outcome
+}
+
+// logPolkaDelivery saves an inbound polka request and its outcome in the delivery log
+func (cfg *apiConfig) logPolkaDelivery(ctx context.Context, headers http.Header, body []byte, signatureVerified bool, outcome webhookOutcome, replayOf uuid.NullUUID) (database.WebhookDelivery, error) {
+	return cfg.databaseQueries.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+		Direction:         webhookInbound,
+		Provider:          sql.NullString{String: polkaProvider, Valid: true},
+		EventID:           nullString(outcome.eventID),
+		EventType:         nullString(outcome.eventType),
+		RequestHeaders:    loggedHeaders(headers),
+		RequestBody:       string(body),
+		ResponseStatus:    sql.NullInt32{Int32: int32(outcome.status), Valid: true},
+		Succeeded:         outcome.err == nil,
+		Error:             nullErrorString(outcome.err),
+		SignatureVerified: signatureVerified,
+		ReplayOf:          replayOf,
+	})
 }
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// handleJWKSGet publishes the public keys so other services can validate chirpy jwts
+func (cfg *apiConfig) handleJWKSGet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// short enough for a newly added key to be picked up before it becomes active
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(cfg.jwtKeys.JWKS()); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
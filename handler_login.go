@@ -2,9 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/google/uuid"
@@ -32,16 +33,15 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// run query
-	potentialUser, err := cfg.databaseQueries.AuthenticateUser(r.Context(), credential.Email)
-	if err != nil {
-		log.Printf("error finding user: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+	potentialUser, err := cfg.checkPassword(r.Context(), credential.Email, credential.Password, clientIP(r))
+	var lockedErr *loginLockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", lockedErr.retryAfter())
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(errorResponse{Error: lockedErr.Error()})
 		return
 	}
-
-	// compare request password with database password
-	if err := auth.CheckPasswordHash(credential.Password, potentialUser.HashedPassword); err != nil {
+	if err == errIncorrectLogin {
 		w.WriteHeader(http.StatusUnauthorized)
 		errorResp := errorResponse{
 			Error: "Incorrect email or password",
@@ -55,9 +55,28 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		w.Write(dat)
 		return
 	}
+	if err != nil {
+		log.Printf("error checking password: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
-	// get servers secret key used in the jwt's signature
-	jwtSigningKey := os.Getenv("SIGNING_KEY")
+	// with two-factor authentication the tokens are only issued by POST /api/login/2fa
+	if potentialUser.TotpEnabledAt.Valid {
+		cfg.writeLoginChallenge(w, r, potentialUser.ID)
+		return
+	}
+
+	cfg.writeLoginResponse(w, r, potentialUser)
+}
+
+// writeLoginResponse issues a new access token and starts a new session for the user
+func (cfg *apiConfig) writeLoginResponse(w http.ResponseWriter, r *http.Request, potentialUser database.User) {
+
+	// only the email is cleared, otherwise logging into one account would reset the guesses against others
+	if err := cfg.databaseQueries.ClearLoginFailures(r.Context(), emailLoginKey(potentialUser.Email)); err != nil {
+		log.Printf("error clearing login failures: %v", err)
+	}
 
 	// Default expiration time is 1 hour
 	const expirationSeconds int = 3600
@@ -65,7 +84,7 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 	// Convert seconds to Duration (need to multiply by time.Second)
 	expirationDuration := time.Duration(expirationSeconds) * time.Second
 
-	tokenString, err := auth.MakeJWT(potentialUser.ID, jwtSigningKey, expirationDuration)
+	tokenString, err := cfg.jwtKeys.MakeJWT(potentialUser.ID, expirationDuration)
 	if err != nil {
 		log.Println("couldn't generate jwt")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -81,35 +100,48 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create struct to store parameters to run sql query that creates new refresh token
+	// every login starts a new token family, refreshing rotates tokens inside it
 	createRefreshTokenParameters := database.CreateRefreshTokenParams{
 		Token:     refreshTokenString,
 		UserID:    potentialUser.ID,
-		ExpiresAt: time.Now().Add(60 * 24 * time.Hour),
+		ExpiresAt: time.Now().Add(refreshTokenDuration),
+		FamilyID:  uuid.New(),
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
 	}
 
 	// add new refresh token to postgres database
 	_, err = cfg.databaseQueries.CreateRefreshToken(r.Context(), createRefreshTokenParameters)
+	if err != nil {
+		log.Printf("error creating refresh token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
 	// response struct
 	type UserWithoutPassword struct {
-		ID           uuid.UUID `json:"id"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Email        string    `json:"email"`
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		ID            uuid.UUID `json:"id"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		Token         string    `json:"token"`
+		RefreshToken  string    `json:"refresh_token"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
+		Handle        string    `json:"handle,omitempty"`
+		EmailVerified bool      `json:"email_verified"`
 	}
 
 	// set response values (user fields without password and with the jwt)
 	user := UserWithoutPassword{
-		ID:           potentialUser.ID,
-		CreatedAt:    potentialUser.CreatedAt,
-		UpdatedAt:    potentialUser.UpdatedAt,
-		Email:        potentialUser.Email,
-		Token:        tokenString,
-		RefreshToken: refreshTokenString,
-		IsChirpyRed:  potentialUser.IsChirpyRed.Bool,
+		ID:            potentialUser.ID,
+		CreatedAt:     potentialUser.CreatedAt,
+		UpdatedAt:     potentialUser.UpdatedAt,
+		Email:         potentialUser.Email,
+		Token:         tokenString,
+		RefreshToken:  refreshTokenString,
+		IsChirpyRed:   potentialUser.IsChirpyRed.Bool,
+		Handle:        potentialUser.Handle.String,
+		EmailVerified: potentialUser.EmailVerifiedAt.Valid,
 	}
 
 	// Set headers before writing response
@@ -122,6 +154,13 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+}
 
-	return
+// clientIP returns the address the request came from without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
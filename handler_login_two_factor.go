@@ -0,0 +1,108 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+
+	type TwoFactorLogin struct {
+		ChallengeToken string `json:"challenge_token"`
+		SecondFactor
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	login := TwoFactorLogin{}
+	if err := decoder.Decode(&login); err != nil {
+		log.Printf("error decoding two-factor login: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := cfg.databaseQueries.GetLoginChallenge(r.Context(), login.ChallengeToken)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Login challenge is invalid or expired"})
+		return
+	}
+	if err != nil {
+		log.Printf("error getting login challenge: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	user, err := cfg.databaseQueries.GetUserByID(r.Context(), challenge.UserID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// wrong codes lock the email like wrong passwords do, and challenges made before the lock can't be used during it
+	lockedUntil, err := cfg.loginLockedUntil(r.Context(), emailLoginKey(user.Email), ipLoginKey(clientIP(r)))
+	if err != nil {
+		log.Printf("error getting login lockouts: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		lockedErr := &loginLockedError{lockedUntil: lockedUntil}
+		w.Header().Set("Retry-After", lockedErr.retryAfter())
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(errorResponse{Error: lockedErr.Error()})
+		return
+	}
+
+	// every guess counts against the challenge, expired or exhausted challenges aren't found
+	_, err = cfg.databaseQueries.AttemptLoginChallenge(r.Context(), database.AttemptLoginChallengeParams{
+		Token:    challenge.Token,
+		Attempts: maxLoginChallengeAttempts,
+	})
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Login challenge is invalid or expired"})
+		return
+	}
+	if err != nil {
+		log.Printf("error attempting login challenge: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// two-factor authentication could have been turned off after the challenge was created
+	valid := false
+	if user.TotpEnabledAt.Valid {
+		valid, err = cfg.checkSecondFactor(r.Context(), user, login.SecondFactor)
+		if err != nil {
+			log.Printf("error checking second factor: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	if !valid {
+		cfg.recordLoginFailures(r.Context(), emailLoginKey(user.Email), ipLoginKey(clientIP(r)))
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Incorrect code"})
+		return
+	}
+
+	// a challenge can only be exchanged once
+	deleted, err := cfg.databaseQueries.DeleteLoginChallenge(r.Context(), challenge.Token)
+	if err != nil {
+		log.Printf("error deleting login challenge: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Login challenge is invalid or expired"})
+		return
+	}
+
+	cfg.writeLoginResponse(w, r, user)
+}
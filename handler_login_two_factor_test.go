@@ -0,0 +1,82 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func TestHandleLoginTwoFactorLockout(t *testing.T) {
+	userID := uuid.New()
+	email := "walt@breakingbad.com"
+	ip := "203.0.113.7"
+
+	tests := []struct {
+		name         string
+		lockedKey    string
+		wantStatus   int
+		wantAttempt  bool
+		wantRetrySet bool
+	}{
+		{name: "Locked email", lockedKey: emailLoginKey(email), wantStatus: http.StatusTooManyRequests, wantRetrySet: true},
+		{name: "Locked ip address", lockedKey: ipLoginKey(ip), wantStatus: http.StatusTooManyRequests, wantRetrySet: true},
+		// the fake challenge has no guesses left, so the attempt is refused once it's made
+		{name: "Not locked", wantStatus: http.StatusUnauthorized, wantAttempt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			fake := newFakeDB(map[string]fakeQuery{
+				// the challenge was made before the lock and hasn't expired
+				"GetLoginChallenge": func(args []driver.Value) fakeResult {
+					return fakeResult{
+						columns: []string{"token", "user_id", "created_at", "expires_at", "attempts"},
+						rows:    [][]driver.Value{{args[0], userID.String(), now.Add(-time.Minute), now.Add(loginChallengeDuration), int64(0)}},
+					}
+				},
+				"GetUserByID": func(args []driver.Value) fakeResult {
+					return fakeResult{
+						columns: []string{"id", "created_at", "updated_at", "email", "hashed_password", "is_chirpy_red", "handle", "totp_secret", "totp_enabled_at", "totp_last_step", "email_verified_at"},
+						rows:    [][]driver.Value{{userID.String(), now, now, email, "hash", false, nil, "JBSWY3DPEHPK3PXP", now, int64(0), nil}},
+					}
+				},
+				"GetLoginLockouts": func(args []driver.Value) fakeResult {
+					result := fakeResult{columns: []string{"key", "failures", "last_failed_at", "locked_until"}}
+					if tt.lockedKey != "" && strings.Contains(fmt.Sprint(args[0]), tt.lockedKey) {
+						result.rows = [][]driver.Value{{tt.lockedKey, int64(6), now, now.Add(time.Minute)}}
+					}
+					return result
+				},
+				"AttemptLoginChallenge": func(args []driver.Value) fakeResult {
+					return fakeResult{columns: []string{"token", "user_id", "created_at", "expires_at", "attempts"}}
+				},
+			})
+			db := openFakeDB(fake)
+			defer db.Close()
+			cfg := &apiConfig{db: db, databaseQueries: database.New(db)}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/login/2fa", strings.NewReader(`{"challenge_token":"challenge","code":"123456"}`))
+			req.RemoteAddr = ip + ":51234"
+			rec := httptest.NewRecorder()
+			cfg.handleLoginTwoFactor(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Retry-After") != ""; got != tt.wantRetrySet {
+				t.Errorf("Retry-After set = %v, want %v", got, tt.wantRetrySet)
+			}
+			if got := fake.called("AttemptLoginChallenge"); got != tt.wantAttempt {
+				t.Errorf("challenge attempted = %v, want %v", got, tt.wantAttempt)
+			}
+		})
+	}
+}
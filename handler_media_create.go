@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleMediaCreate(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// leave some room for the multipart boundaries and headers
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaSize+1<<20)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(errorResponse{Error: "File is too large"})
+			return
+		}
+		log.Printf("error reading uploaded file: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Request must have a file field"})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxMediaSize+1))
+	if err != nil {
+		log.Printf("error reading uploaded file: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(content) > maxMediaSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(errorResponse{Error: "File is too large"})
+		return
+	}
+
+	// the content type sent by the client can't be trusted, sniff it from the bytes instead
+	contentType := http.DetectContentType(content)
+	if !allowedMediaTypes[contentType] {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		json.NewEncoder(w).Encode(errorResponse{Error: "File type is not supported"})
+		return
+	}
+
+	// the blob is stored under the hash of its content
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if err := cfg.mediaStorage.Put(r.Context(), hash, bytes.NewReader(content)); err != nil {
+		log.Printf("error storing media: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	dbMedia, err := cfg.databaseQueries.CreateMediaFile(r.Context(), database.CreateMediaFileParams{
+		UserID:      userID,
+		Sha256:      hash,
+		ContentType: contentType,
+		SizeBytes:   int64(len(content)),
+	})
+	if err != nil {
+		log.Printf("error creating media: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(mediaFromDatabase(dbMedia)); err != nil {
+		log.Printf("error encoding response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
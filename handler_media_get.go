@@ -0,0 +1,47 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/storage"
+)
+
+func (cfg *apiConfig) handleMediaGet(w http.ResponseWriter, r *http.Request) {
+
+	hash := r.PathValue("sha256")
+
+	dbMedia, err := cfg.databaseQueries.GetMediaFileBySHA256(r.Context(), hash)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting media: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	blob, err := cfg.mediaStorage.Open(r.Context(), dbMedia.Sha256)
+	if errors.Is(err, storage.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("error opening media: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	// the url is the hash of the content, so it can be cached forever
+	w.Header().Set("Content-Type", dbMedia.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", `"`+dbMedia.Sha256+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent answers If-None-Match and range requests
+	http.ServeContent(w, r, "", dbMedia.CreatedAt, blob)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// the consent page asks for the password itself so the client never sees it,
+// html/template escapes the client name since anyone can register a client
+var consentPage = template.Must(template.New("consent").Parse(`<html>
+	<head>
+		<title>Authorize {{.ClientName}} - Chirpy</title>
+	</head>
+	<body>
+		{{if .Fatal}}
+		<h1>Can't authorize this app</h1>
+		<p>{{.Error}}</p>
+		{{else}}
+		<h1>{{.ClientName}} wants to access your Chirpy account</h1>
+		<p>It will be able to:</p>
+		<ul>
+			{{range .Scopes}}<li>{{.}}</li>{{end}}
+		</ul>
+		{{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+		<form method="POST" action="/oauth/authorize">
+			<input type="hidden" name="response_type" value="code">
+			<input type="hidden" name="client_id" value="{{.ClientID}}">
+			<input type="hidden" name="redirect_uri" value="{{.RedirectURI}}">
+			<input type="hidden" name="scope" value="{{.Scope}}">
+			<input type="hidden" name="state" value="{{.State}}">
+			<input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+			<input type="hidden" name="code_challenge_method" value="S256">
+			<p><label>Email <input type="email" name="email" value="{{.Email}}" autocomplete="username"></label></p>
+			<p><label>Password <input type="password" name="password" autocomplete="current-password"></label></p>
+			<p><label>Two-factor code, if enabled <input type="text" name="code" autocomplete="one-time-code"></label></p>
+			<button type="submit" name="decision" value="approve">Allow</button>
+			<button type="submit" name="decision" value="deny">Deny</button>
+		</form>
+		{{end}}
+	</body>
+</html>
+`))
+
+type consentPageData struct {
+	// fatal errors can't be sent back to the client because its redirect uri isn't trusted
+	Fatal         bool
+	Error         string
+	ClientName    string
+	ClientID      string
+	RedirectURI   string
+	Scope         string
+	Scopes        []string
+	State         string
+	CodeChallenge string
+	Email         string
+}
+
+// authorizeRequest is a validated request to GET or POST /oauth/authorize
+type authorizeRequest struct {
+	Client        database.OauthClient
+	RedirectURI   string
+	Scopes        []string
+	State         string
+	CodeChallenge string
+}
+
+// errUntrustedRedirect means the client or its redirect uri is unknown, so errors can't be redirected to it
+var errUntrustedRedirect = errors.New("Unknown client or redirect URI")
+
+// parseAuthorizeRequest checks the client and redirect uri first, once those are trusted any other
+// problem is returned as an oauth error to redirect back to the client (RFC 6749 section 4.1.2.1)
+func (cfg *apiConfig) parseAuthorizeRequest(ctx context.Context, params url.Values) (authorizeRequest, *oauthErrorResponse, error) {
+	clientID, err := uuid.Parse(params.Get("client_id"))
+	if err != nil {
+		return authorizeRequest{}, nil, errUntrustedRedirect
+	}
+	client, err := cfg.databaseQueries.GetOAuthClient(ctx, clientID)
+	if err == sql.ErrNoRows {
+		return authorizeRequest{}, nil, errUntrustedRedirect
+	}
+	if err != nil {
+		return authorizeRequest{}, nil, err
+	}
+
+	// redirect uris have to match one of the registered ones exactly
+	redirectURI := params.Get("redirect_uri")
+	if !slices.Contains(client.RedirectUris, redirectURI) {
+		return authorizeRequest{}, nil, errUntrustedRedirect
+	}
+
+	request := authorizeRequest{
+		Client:        client,
+		RedirectURI:   redirectURI,
+		State:         params.Get("state"),
+		CodeChallenge: params.Get("code_challenge"),
+	}
+
+	if params.Get("response_type") != "code" {
+		return request, &oauthErrorResponse{Error: "unsupported_response_type", ErrorDescription: "response_type must be code"}, nil
+	}
+	// every client uses pkce, even confidential ones
+	if request.CodeChallenge == "" || params.Get("code_challenge_method") != "S256" {
+		return request, &oauthErrorResponse{Error: "invalid_request", ErrorDescription: "code_challenge with code_challenge_method S256 is required"}, nil
+	}
+	request.Scopes, err = parseOAuthScopes(params.Get("scope"))
+	if err != nil {
+		return request, &oauthErrorResponse{Error: "invalid_scope", ErrorDescription: err.Error()}, nil
+	}
+	return request, nil, nil
+}
+
+// redirectAuthorizeError sends the user back to the client with the error
+func redirectAuthorizeError(w http.ResponseWriter, r *http.Request, request authorizeRequest, oauthErr oauthErrorResponse) {
+	params := url.Values{}
+	params.Set("error", oauthErr.Error)
+	if oauthErr.ErrorDescription != "" {
+		params.Set("error_description", oauthErr.ErrorDescription)
+	}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	http.Redirect(w, r, oauthRedirect(request.RedirectURI, params), http.StatusFound)
+}
+
+func writeConsentPage(w http.ResponseWriter, status int, data consentPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// the page can't be framed, otherwise another site could trick users into clicking allow
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := consentPage.Execute(w, data); err != nil {
+		log.Printf("error rendering consent page: %v", err)
+	}
+}
+
+func consentPageFor(request authorizeRequest) consentPageData {
+	scopes := []string{}
+	for _, scope := range request.Scopes {
+		scopes = append(scopes, oauthScopeDescriptions[scope])
+	}
+	return consentPageData{
+		ClientName:    request.Client.Name,
+		ClientID:      request.Client.ID.String(),
+		RedirectURI:   request.RedirectURI,
+		Scope:         strings.Join(request.Scopes, " "),
+		Scopes:        scopes,
+		State:         request.State,
+		CodeChallenge: request.CodeChallenge,
+	}
+}
+
+func (cfg *apiConfig) handleOAuthAuthorizeGet(w http.ResponseWriter, r *http.Request) {
+	request, oauthErr, err := cfg.parseAuthorizeRequest(r.Context(), r.URL.Query())
+	if err == errUntrustedRedirect {
+		writeConsentPage(w, http.StatusBadRequest, consentPageData{Fatal: true, Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("error getting oauth client: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if oauthErr != nil {
+		redirectAuthorizeError(w, r, request, *oauthErr)
+		return
+	}
+
+	writeConsentPage(w, http.StatusOK, consentPageFor(request))
+}
+
+func (cfg *apiConfig) handleOAuthAuthorizePost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("error parsing consent form: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// the parameters come back from the hidden fields and are checked again
+	request, oauthErr, err := cfg.parseAuthorizeRequest(r.Context(), r.PostForm)
+	if err == errUntrustedRedirect {
+		writeConsentPage(w, http.StatusBadRequest, consentPageData{Fatal: true, Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("error getting oauth client: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if oauthErr != nil {
+		redirectAuthorizeError(w, r, request, *oauthErr)
+		return
+	}
+
+	if r.PostForm.Get("decision") != "approve" {
+		redirectAuthorizeError(w, r, request, oauthErrorResponse{Error: "access_denied", ErrorDescription: "The user denied access"})
+		return
+	}
+
+	// the password is needed to approve, so a forged form from another site can't grant access
+	email := r.PostForm.Get("email")
+	page := consentPageFor(request)
+	page.Email = email
+
+	user, err := cfg.checkPassword(r.Context(), email, r.PostForm.Get("password"), clientIP(r))
+	var lockedErr *loginLockedError
+	if errors.As(err, &lockedErr) {
+		w.Header().Set("Retry-After", lockedErr.retryAfter())
+		page.Error = lockedErr.Error()
+		writeConsentPage(w, http.StatusTooManyRequests, page)
+		return
+	}
+	if err == errIncorrectLogin {
+		page.Error = err.Error()
+		writeConsentPage(w, http.StatusUnauthorized, page)
+		return
+	}
+	if err != nil {
+		log.Printf("error checking password: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		code := strings.TrimSpace(r.PostForm.Get("code"))
+		if code == "" {
+			page.Error = "Enter the code from your authenticator app or a recovery code"
+			writeConsentPage(w, http.StatusUnauthorized, page)
+			return
+		}
+		// totp codes are 6 digits, anything else is tried as a recovery code
+		factor := SecondFactor{RecoveryCode: code}
+		if len(code) == 6 && strings.Trim(code, "0123456789") == "" {
+			factor = SecondFactor{Code: code}
+		}
+		ok, err := cfg.checkSecondFactor(r.Context(), user, factor)
+		if err != nil {
+			log.Printf("error checking second factor: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			cfg.recordLoginFailures(r.Context(), emailLoginKey(user.Email), ipLoginKey(clientIP(r)))
+			page.Error = "Invalid code"
+			writeConsentPage(w, http.StatusUnauthorized, page)
+			return
+		}
+	}
+
+	if err := cfg.databaseQueries.ClearLoginFailures(r.Context(), emailLoginKey(user.Email)); err != nil {
+		log.Printf("error clearing login failures: %v", err)
+	}
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("error generating authorization code: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// only the hash is stored, the code itself only goes to the client through the redirect
+	err = cfg.databaseQueries.CreateAuthorizationCode(r.Context(), database.CreateAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      request.Client.ID,
+		UserID:        user.ID,
+		RedirectUri:   request.RedirectURI,
+		Scopes:        request.Scopes,
+		CodeChallenge: request.CodeChallenge,
+		FamilyID:      uuid.New(),
+		ExpiresAt:     time.Now().Add(authorizationCodeDuration),
+	})
+	if err != nil {
+		log.Printf("error creating authorization code: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	params := url.Values{}
+	params.Set("code", code)
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	http.Redirect(w, r, oauthRedirect(request.RedirectURI, params), http.StatusFound)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleOAuthClientsCreate(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	reqClient := OAuthClient{}
+	if err := decoder.Decode(&reqClient); err != nil {
+		log.Printf("error decoding oauth client: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(reqClient.Name)
+	if name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Client name can't be empty"})
+		return
+	}
+
+	if len(reqClient.RedirectURIs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Client needs at least one redirect URI"})
+		return
+	}
+	for _, redirectURI := range reqClient.RedirectURIs {
+		if err := parseRedirectURI(redirectURI); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	// public clients have no secret, only the hash of a confidential client's secret is stored
+	secret := ""
+	secretHash := sql.NullString{}
+	if reqClient.Confidential {
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			log.Printf("error generating client secret: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	dbClient, err := cfg.databaseQueries.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		OwnerID:      userID,
+		Name:         name,
+		SecretHash:   secretHash,
+		RedirectUris: reqClient.RedirectURIs,
+	})
+	if err != nil {
+		log.Printf("error creating oauth client: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	client := oauthClientFromDatabase(dbClient)
+	client.Secret = secret
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(client); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
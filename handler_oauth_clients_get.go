@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/auth"
+)
+
+func (cfg *apiConfig) handleOAuthClientsGet(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	dbClients, err := cfg.databaseQueries.GetOAuthClients(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting oauth clients: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	clients := []OAuthClient{}
+	for _, dbClient := range dbClients {
+		clients = append(clients, oauthClientFromDatabase(dbClient))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(clients); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
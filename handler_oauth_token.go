@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// authenticateOAuthClient returns the client of a token request, confidential clients send their secret
+// with basic auth or in the form, public clients only send their id
+func (cfg *apiConfig) authenticateOAuthClient(r *http.Request) (database.OauthClient, bool, error) {
+	clientIDString, secret, hasBasicAuth := r.BasicAuth()
+	if !hasBasicAuth {
+		clientIDString = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	clientID, err := uuid.Parse(clientIDString)
+	if err != nil {
+		return database.OauthClient{}, false, nil
+	}
+	client, err := cfg.databaseQueries.GetOAuthClient(r.Context(), clientID)
+	if err == sql.ErrNoRows {
+		return database.OauthClient{}, false, nil
+	}
+	if err != nil {
+		return database.OauthClient{}, false, err
+	}
+
+	if client.SecretHash.Valid {
+		secretHash := auth.HashToken(secret)
+		if subtle.ConstantTimeCompare([]byte(secretHash), []byte(client.SecretHash.String)) != 1 {
+			return database.OauthClient{}, false, nil
+		}
+	}
+	return client, true, nil
+}
+
+func (cfg *apiConfig) handleOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "The body must be form encoded")
+		return
+	}
+
+	client, ok, err := cfg.authenticateOAuthClient(r)
+	if err != nil {
+		log.Printf("error authenticating oauth client: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Unknown client or wrong client secret")
+		return
+	}
+
+	var refreshToken database.RefreshToken
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		refreshToken, ok, err = cfg.exchangeAuthorizationCode(r, client)
+	case "refresh_token":
+		// the refresh token keeps the scopes it was granted with
+		refreshToken, err = cfg.rotateRefreshToken(r.Context(), r.PostForm.Get("refresh_token"), uuid.NullUUID{UUID: client.ID, Valid: true})
+		ok = err != errInvalidRefreshToken
+		if !ok {
+			err = nil
+		}
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+		return
+	}
+	if err != nil {
+		log.Printf("error issuing oauth tokens: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "The code or refresh token is invalid, expired or was issued to another client")
+		return
+	}
+
+	accessToken, err := cfg.jwtKeys.MakeScopedJWT(refreshToken.UserID, client.ID.String(), refreshToken.Scopes, oauthAccessTokenDuration)
+	if err != nil {
+		log.Println("couldn't generate jwt")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	type tokenResponse struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}
+
+	response := tokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthAccessTokenDuration.Seconds()),
+		RefreshToken: refreshToken.Token,
+		Scope:        strings.Join(refreshToken.Scopes, " "),
+	}
+
+	// tokens must not be cached (RFC 6749 section 5.1)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
+
+// exchangeAuthorizationCode uses the code and starts the session it was issued for, ok is false for invalid codes
+func (cfg *apiConfig) exchangeAuthorizationCode(r *http.Request, client database.OauthClient) (database.RefreshToken, bool, error) {
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		return database.RefreshToken{}, false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
+
+	// lock the code so it can't be exchanged twice at the same time
+	code, err := qtx.GetAuthorizationCodeForUpdate(r.Context(), auth.HashToken(r.PostForm.Get("code")))
+	if err == sql.ErrNoRows {
+		return database.RefreshToken{}, false, nil
+	}
+	if err != nil {
+		return database.RefreshToken{}, false, err
+	}
+
+	if code.ClientID != client.ID {
+		log.Println("authorization code used by another client")
+		return database.RefreshToken{}, false, nil
+	}
+
+	// a code being used again means it was intercepted, so the tokens it was exchanged for are revoked
+	if code.UsedAt.Valid {
+		log.Printf("authorization code reused, revoking family %v", code.FamilyID)
+		if err := qtx.RevokeRefreshTokenFamily(r.Context(), code.FamilyID); err != nil {
+			return database.RefreshToken{}, false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return database.RefreshToken{}, false, err
+		}
+		return database.RefreshToken{}, false, nil
+	}
+
+	if code.ExpiresAt.Before(time.Now()) {
+		log.Printf("authorization code expired: %v", code.ExpiresAt)
+		return database.RefreshToken{}, false, nil
+	}
+	if r.PostForm.Get("redirect_uri") != code.RedirectUri {
+		log.Println("authorization code used with another redirect uri")
+		return database.RefreshToken{}, false, nil
+	}
+	// only the app that started the flow knows the verifier, even if the code leaked
+	if !auth.VerifyCodeChallenge(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		log.Println("authorization code used with wrong code verifier")
+		return database.RefreshToken{}, false, nil
+	}
+
+	if err := qtx.UseAuthorizationCode(r.Context(), code.CodeHash); err != nil {
+		return database.RefreshToken{}, false, err
+	}
+
+	refreshTokenString, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.RefreshToken{}, false, err
+	}
+
+	refreshToken, err := qtx.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:     refreshTokenString,
+		UserID:    code.UserID,
+		ExpiresAt: time.Now().Add(refreshTokenDuration),
+		FamilyID:  code.FamilyID,
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+		ClientID:  uuid.NullUUID{UUID: client.ID, Valid: true},
+		Scopes:    code.Scopes,
+	})
+	if err != nil {
+		return database.RefreshToken{}, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.RefreshToken{}, false, err
+	}
+	return refreshToken, true, nil
+}
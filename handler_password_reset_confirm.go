@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handlePasswordResetConfirm(w http.ResponseWriter, r *http.Request) {
+
+	type PasswordResetConfirmation struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	confirmation := PasswordResetConfirmation{}
+	if err := decoder.Decode(&confirmation); err != nil {
+		log.Printf("error decoding password reset confirmation: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if confirmation.Password == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Password can't be empty"})
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(confirmation.Password)
+	if err != nil {
+		log.Printf("error hashing new password: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
+
+	// marking the token as used and changing the password happen together, so a token works once
+	resetToken, err := qtx.UsePasswordResetToken(r.Context(), auth.HashToken(confirmation.Token))
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Reset token is invalid or expired"})
+		return
+	}
+	if err != nil {
+		log.Printf("error using password reset token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.SetUserPassword(r.Context(), database.SetUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             resetToken.UserID,
+	})
+	if err != nil {
+		log.Printf("error setting password: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// the other reset emails that are still waiting can't be used anymore
+	if err := qtx.ExpirePasswordResetTokens(r.Context(), resetToken.UserID); err != nil {
+		log.Printf("error expiring password reset tokens: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// whoever knew the old password is signed out everywhere
+	if err := qtx.RevokeAllRefreshTokens(r.Context(), resetToken.UserID); err != nil {
+		log.Printf("error revoking refresh tokens: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing password reset: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
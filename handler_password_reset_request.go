@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/mailer"
+)
+
+const passwordResetDuration = time.Hour
+
+func (cfg *apiConfig) handlePasswordResetRequest(w http.ResponseWriter, r *http.Request) {
+
+	type PasswordResetRequest struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	resetRequest := PasswordResetRequest{}
+	if err := decoder.Decode(&resetRequest); err != nil {
+		log.Printf("error decoding password reset request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// the response is the same whether or not the email has an account, so it can't be used to find accounts
+	user, err := cfg.databaseQueries.AuthenticateUser(r.Context(), resetRequest.Email)
+	if err == sql.ErrNoRows {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		log.Printf("error finding user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resetToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("error generating password reset token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// only the hash is stored, the token itself is only in the email
+	err = cfg.databaseQueries.CreatePasswordResetToken(r.Context(), database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(resetToken),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(passwordResetDuration),
+	})
+	if err != nil {
+		log.Printf("error creating password reset token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	message := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Chirpy account.\n\n"+
+			"Your reset token is: %s\n\n"+
+			"It expires in %s and can only be used once. If it wasn't you, you can ignore this email.\n",
+			resetToken, passwordResetDuration),
+	}
+	// sent in the background so the response time doesn't depend on the mail server
+	go func() {
+		if err := cfg.mailer.Send(context.Background(), message); err != nil {
+			log.Printf("error sending password reset email: %v", err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
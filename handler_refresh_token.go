@@ -1,59 +1,130 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"os"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
 )
 
-func (cfg *apiConfig) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+// refreshTokenDuration is how long a refresh token lives, every rotation starts the count again
+const refreshTokenDuration = 60 * 24 * time.Hour
 
-	// check if request has authorization headers
-	jwtString, err := auth.GetBearerToken(r.Header)
+// errInvalidRefreshToken is returned for refresh tokens that are unknown, expired, revoked or issued to another client
+var errInvalidRefreshToken = errors.New("invalid refresh token")
+
+// rotateRefreshToken replaces the refresh token with a new one in the same family and returns it,
+// clientID is only valid for tokens issued to oauth clients
+func (cfg *apiConfig) rotateRefreshToken(ctx context.Context, tokenString string, clientID uuid.NullUUID) (database.RefreshToken, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("error getting bearer token: %v", err)
-		w.WriteHeader(http.StatusUnauthorized)
-		return
+		return database.RefreshToken{}, err
 	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
 
-	// run sql query that searches the refresh token in the database
-	refreshToken, err := cfg.databaseQueries.GetUserFromRefreshToken(r.Context(), jwtString)
+	// lock the token so two concurrent refreshes can't both rotate it
+	refreshToken, err := qtx.GetRefreshTokenForUpdate(ctx, tokenString)
+	// if refresh token is not found in database
+	if err == sql.ErrNoRows {
+		log.Println("refresh token not found in database")
+		return database.RefreshToken{}, errInvalidRefreshToken
+	}
 	if err != nil {
-		log.Printf("error getting user with refresh token: %v", err)
-		w.WriteHeader(http.StatusUnauthorized)
-		return
+		return database.RefreshToken{}, err
+	}
+
+	// a token can only be refreshed through the endpoint it was issued by
+	if refreshToken.ClientID != clientID {
+		log.Println("refresh token used by another client")
+		return database.RefreshToken{}, errInvalidRefreshToken
+	}
+
+	// a revoked token being used again means it was stolen, so every token in its family is revoked
+	if refreshToken.RevokedAt.Valid {
+		log.Printf("revoked refresh token reused, revoking family %v", refreshToken.FamilyID)
+		if err := qtx.RevokeRefreshTokenFamily(ctx, refreshToken.FamilyID); err != nil {
+			return database.RefreshToken{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return database.RefreshToken{}, err
+		}
+		return database.RefreshToken{}, errInvalidRefreshToken
 	}
 
 	// if refresh token is expired
 	if refreshToken.ExpiresAt.Before(time.Now()) {
 		log.Printf("refresh token expired: %v", refreshToken.ExpiresAt)
-		w.WriteHeader(http.StatusUnauthorized)
-		return
+		return database.RefreshToken{}, errInvalidRefreshToken
 	}
 
-	// if refresh token is revoked
-	if refreshToken.RevokedAt.Valid {
-		log.Printf("refresh token has been revoked at: %v", refreshToken.RevokedAt)
+	// the new refresh token replaces the one that was just used
+	newRefreshTokenString, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.RefreshToken{}, err
+	}
+
+	newRefreshToken, err := qtx.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     newRefreshTokenString,
+		UserID:    refreshToken.UserID,
+		ExpiresAt: time.Now().Add(refreshTokenDuration),
+		FamilyID:  refreshToken.FamilyID,
+		// the session keeps the client it was started from
+		UserAgent: refreshToken.UserAgent,
+		IpAddress: refreshToken.IpAddress,
+		ClientID:  refreshToken.ClientID,
+		Scopes:    refreshToken.Scopes,
+	})
+	if err != nil {
+		return database.RefreshToken{}, err
+	}
+
+	err = qtx.RotateRefreshToken(ctx, database.RotateRefreshTokenParams{
+		Token:      refreshToken.Token,
+		ReplacedBy: sql.NullString{String: newRefreshTokenString, Valid: true},
+	})
+	if err != nil {
+		return database.RefreshToken{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.RefreshToken{}, err
+	}
+	return newRefreshToken, nil
+}
+
+func (cfg *apiConfig) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	// if refresh token is not found in database
-	if err == sql.ErrNoRows {
-		log.Printf("refresh token not found in database: %v", refreshToken.ExpiresAt)
+	// tokens issued to oauth clients are refreshed through POST /oauth/token
+	newRefreshToken, err := cfg.rotateRefreshToken(r.Context(), jwtString, uuid.NullUUID{})
+	if err == errInvalidRefreshToken {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
+	if err != nil {
+		log.Printf("error rotating refresh token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
 	// create access token string
-	jwtSigningKey := os.Getenv("SIGNING_KEY")
 	timeToExpire := time.Hour
-	accessTokenString, err := auth.MakeJWT(refreshToken.UserID, jwtSigningKey, timeToExpire)
+	accessTokenString, err := cfg.jwtKeys.MakeJWT(newRefreshToken.UserID, timeToExpire)
 	if err != nil {
 		log.Println("couldn't generate jwt")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -61,11 +132,13 @@ func (cfg *apiConfig) handleRefreshToken(w http.ResponseWriter, r *http.Request)
 	}
 
 	type jwtResponse struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	response := jwtResponse{
-		Token: accessTokenString,
+		Token:        accessTokenString,
+		RefreshToken: newRefreshToken.Token,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleTokensCreate(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// only a jwt can mint tokens, a personal access token can't create more of itself
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	reqToken := PersonalAccessToken{}
+	if err := decoder.Decode(&reqToken); err != nil {
+		log.Printf("error decoding token: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(reqToken.Name)
+	if name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Token name can't be empty"})
+		return
+	}
+
+	scopes := []string{}
+	seenScopes := map[string]bool{}
+	for _, scope := range reqToken.Scopes {
+		if !personalAccessTokenScopes[scope] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: fmt.Sprintf("Unknown scope %q", scope)})
+			return
+		}
+		if !seenScopes[scope] {
+			seenScopes[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Token needs at least one scope"})
+		return
+	}
+
+	// tokens without an expiry last until they're deleted
+	expiresAt := sql.NullTime{}
+	if reqToken.ExpiresAt != nil {
+		if !reqToken.ExpiresAt.After(time.Now()) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: "expires_at must be in the future"})
+			return
+		}
+		// stored in local time like time.Now(), the column has no time zone
+		expiresAt = sql.NullTime{Time: reqToken.ExpiresAt.Local(), Valid: true}
+	}
+
+	tokenString, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		log.Printf("error generating personal access token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// only the hash is stored, the token can't be shown again
+	dbToken, err := cfg.databaseQueries.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: auth.HashToken(tokenString),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Printf("error creating personal access token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	token := personalAccessTokenFromDatabase(dbToken)
+	token.Token = tokenString
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
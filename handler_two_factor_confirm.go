@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/totp"
+)
+
+// handleTwoFactorConfirm turns two-factor authentication on once the app shows a valid code
+func (cfg *apiConfig) handleTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	factor := SecondFactor{}
+	if err := decoder.Decode(&factor); err != nil {
+		log.Printf("error decoding code: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.databaseQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Two-factor authentication is already enabled"})
+		return
+	}
+	if !user.TotpSecret.Valid {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Two-factor authentication enrollment wasn't started"})
+		return
+	}
+
+	step, ok := totp.Validate(user.TotpSecret.String, factor.Code, time.Now())
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Incorrect code"})
+		return
+	}
+
+	// the codes are only shown once, only their hashes are stored
+	recoveryCodes, recoveryCodeHashes, err := makeRecoveryCodes()
+	if err != nil {
+		log.Printf("error generating recovery codes: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
+
+	// the confirming code's step counts as used
+	enabled, err := qtx.EnableTOTP(r.Context(), database.EnableTOTPParams{
+		TotpLastStep: step,
+		ID:           userID,
+	})
+	if err != nil {
+		log.Printf("error enabling totp: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// a concurrent request confirmed first
+	if enabled == 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Two-factor authentication is already enabled"})
+		return
+	}
+
+	if err := qtx.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+		log.Printf("error deleting recovery codes: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err = qtx.CreateRecoveryCodes(r.Context(), database.CreateRecoveryCodesParams{
+		UserID:     userID,
+		CodeHashes: recoveryCodeHashes,
+	})
+	if err != nil {
+		log.Printf("error creating recovery codes: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing two-factor authentication: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	type RecoveryCodes struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(RecoveryCodes{RecoveryCodes: recoveryCodes}); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
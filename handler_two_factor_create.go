@@ -0,0 +1,79 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/totp"
+)
+
+// handleTwoFactorCreate starts enrolling, two-factor authentication is only on after it's confirmed
+func (cfg *apiConfig) handleTwoFactorCreate(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.databaseQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Printf("error generating totp secret: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// enrolling again before confirming replaces the pending secret
+	updated, err := cfg.databaseQueries.SetTOTPSecret(r.Context(), database.SetTOTPSecretParams{
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+		ID:         userID,
+	})
+	if err != nil {
+		log.Printf("error setting totp secret: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if updated == 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Two-factor authentication is already enabled"})
+		return
+	}
+
+	type TwoFactorEnrollment struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauth_uri"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	enrollment := TwoFactorEnrollment{
+		Secret:     secret,
+		OtpauthURI: totp.URI(totpIssuer, user.Email, secret),
+	}
+	if err := json.NewEncoder(w).Encode(enrollment); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/chirptext"
 	"github.com/troclaux/chirpy/internal/database"
 )
 
@@ -18,6 +23,36 @@ type User struct {
 	Email       string    `json:"email"`
 	Password    string    `json:"password"`
 	IsChirpyRed bool      `json:"is_chirpy_red"`
+	Handle      string    `json:"handle,omitempty"`
+	// unverified accounts can't post chirps
+	EmailVerified bool `json:"email_verified"`
+	// the new email waiting to be verified, the current one is kept until then
+	PendingEmail string `json:"pending_email,omitempty"`
+}
+
+// parseHandle validates the handle users are mentioned by, an empty handle means no handle
+func parseHandle(handle string) (sql.NullString, error) {
+	handle = strings.TrimPrefix(handle, "@")
+	if handle == "" {
+		return sql.NullString{}, nil
+	}
+	if !chirptext.ValidHandle(handle) {
+		return sql.NullString{}, errors.New("Handle must have 1 to 15 letters, digits or underscores")
+	}
+	// handles are matched against lowercased mentions
+	return sql.NullString{String: strings.ToLower(handle), Valid: true}, nil
+}
+
+// takenFieldError tells the user which unique field of their account is already in use
+func takenFieldError(err error) (errorResponse, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolation {
+		return errorResponse{}, false
+	}
+	if pqErr.Constraint == "users_handle_key" {
+		return errorResponse{Error: "Handle is already taken"}, true
+	}
+	return errorResponse{Error: "Email is already taken"}, true
 }
 
 func (cfg *apiConfig) handleUsersCreate(w http.ResponseWriter, r *http.Request) {
@@ -32,6 +67,20 @@ func (cfg *apiConfig) handleUsersCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	email, err := parseEmail(reqUser.Email)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+
+	handle, err := parseHandle(reqUser.Handle)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+
 	hash, err := auth.HashPassword(reqUser.Password)
 	if err != nil {
 		log.Printf("error hashing password: %v", err)
@@ -41,26 +90,59 @@ func (cfg *apiConfig) handleUsersCreate(w http.ResponseWriter, r *http.Request)
 
 	// create parameters for query created by sqlc in database package
 	parameters := database.CreateUserParams{
-		Email:          reqUser.Email,
+		Email:          email,
 		HashedPassword: hash,
+		Handle:         handle,
 	}
 
+	// the user and their email verification are created together
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
+
 	// http.Request.Context() cancels the database query if the http request is cancelled or times out
 	// use sqlc generated code to create a new user in the database and store it in newUser variable
-	newUser, err := cfg.databaseQueries.CreateUser(r.Context(), parameters)
+	newUser, err := qtx.CreateUser(r.Context(), parameters)
+	if errResp, taken := takenFieldError(err); taken {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(errResp)
+		return
+	}
 	if err != nil {
 		log.Printf("error creating user: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	verificationToken, err := startEmailVerification(r.Context(), qtx, newUser.ID, newUser.Email)
+	if err != nil {
+		log.Printf("error creating email verification: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cfg.sendEmailVerification(newUser.Email, verificationToken)
+
 	userResponse := User{
-		ID:          newUser.ID,
-		CreatedAt:   newUser.CreatedAt,
-		UpdatedAt:   newUser.UpdatedAt,
-		Email:       newUser.Email,
-		Password:    newUser.HashedPassword,
-		IsChirpyRed: newUser.IsChirpyRed.Bool,
+		ID:            newUser.ID,
+		CreatedAt:     newUser.CreatedAt,
+		UpdatedAt:     newUser.UpdatedAt,
+		Email:         newUser.Email,
+		Password:      newUser.HashedPassword,
+		IsChirpyRed:   newUser.IsChirpyRed.Bool,
+		Handle:        newUser.Handle.String,
+		EmailVerified: newUser.EmailVerifiedAt.Valid,
 	}
 
 	// Set headers before writing response
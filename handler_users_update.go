@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"os"
 
 	"github.com/troclaux/chirpy/internal/auth"
 	"github.com/troclaux/chirpy/internal/database"
@@ -14,25 +13,21 @@ import (
 type Credentials struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// optional, the current handle is kept when it's empty
+	Handle string `json:"handle"`
 }
 
 func (cfg *apiConfig) handleUsersUpdate(w http.ResponseWriter, r *http.Request) {
 
-	// check if request has authorization headers
-	jwtString, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		log.Printf("error getting bearer token: %v", err)
-		w.WriteHeader(http.StatusUnauthorized)
+	// accepts a jwt or a personal access token with the profile:write scope
+	userID, err := cfg.authenticate(r, scopeProfileWrite)
+	if err == errMissingScope {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
 		return
 	}
-
-	// get the server's signing key from .env
-	jwtSigningKey := os.Getenv("SIGNING_KEY")
-
-	// validate jwt string
-	userID, err := auth.ValidateJWT(jwtString, jwtSigningKey)
 	if err != nil {
-		log.Printf("error validating jwt: %v", err)
+		log.Printf("error authenticating request: %v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -47,6 +42,20 @@ func (cfg *apiConfig) handleUsersUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	email, err := parseEmail(credentials.Email)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+
+	handle, err := parseHandle(credentials.Handle)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+
 	hashedPassword, err := auth.HashPassword(credentials.Password)
 	if err != nil {
 		log.Printf("error hashing new password: %v", err)
@@ -54,13 +63,17 @@ func (cfg *apiConfig) handleUsersUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	updateUserParams := database.UpdateUserParams{
-		Email:          credentials.Email,
-		HashedPassword: hashedPassword,
-		ID:             userID,
+	// the handle is changed in the same transaction as the rest of the user
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
 
-	updatedUser, err := cfg.databaseQueries.UpdateUser(r.Context(), updateUserParams)
+	currentUser, err := qtx.GetUserByID(r.Context(), userID)
 
 	// if user is not found on database by id
 	if err == sql.ErrNoRows {
@@ -68,14 +81,95 @@ func (cfg *apiConfig) handleUsersUpdate(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// a new email only replaces the current one once it's verified
+	pendingEmail := ""
+	verificationToken := ""
+	if email != currentUser.Email {
+		_, err := qtx.AuthenticateUser(r.Context(), email)
+		if err == nil {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(errorResponse{Error: "Email is already taken"})
+			return
+		}
+		if err != sql.ErrNoRows {
+			log.Printf("error finding user by email: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		verificationToken, err = startEmailVerification(r.Context(), qtx, userID, email)
+		if err != nil {
+			log.Printf("error creating email verification: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		pendingEmail = email
+	}
+
+	updatedUser, err := qtx.UpdateUser(r.Context(), database.UpdateUserParams{
+		Email:          currentUser.Email,
+		HashedPassword: hashedPassword,
+		ID:             userID,
+	})
+	if errResp, taken := takenFieldError(err); taken {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(errResp)
+		return
+	}
+	if err != nil {
+		log.Printf("error updating user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if handle.Valid {
+		updatedUser, err = qtx.SetUserHandle(r.Context(), database.SetUserHandleParams{
+			Handle: handle,
+			ID:     userID,
+		})
+		if errResp, taken := takenFieldError(err); taken {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(errResp)
+			return
+		}
+		if err != nil {
+			log.Printf("error setting user handle: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := enqueueWebhookEvent(r.Context(), qtx, userID, webhookUserUpdated, webhookUserFromDatabase(updatedUser)); err != nil {
+		log.Printf("error enqueueing webhook event: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing user update: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if pendingEmail != "" {
+		cfg.sendEmailVerification(pendingEmail, verificationToken)
+	}
 
 	userResponse := User{
-		ID:          updatedUser.ID,
-		CreatedAt:   updatedUser.CreatedAt,
-		UpdatedAt:   updatedUser.UpdatedAt,
-		Email:       updatedUser.Email,
-		Password:    updatedUser.HashedPassword,
-		IsChirpyRed: updatedUser.IsChirpyRed.Bool,
+		ID:            updatedUser.ID,
+		CreatedAt:     updatedUser.CreatedAt,
+		UpdatedAt:     updatedUser.UpdatedAt,
+		Email:         updatedUser.Email,
+		Password:      updatedUser.HashedPassword,
+		IsChirpyRed:   updatedUser.IsChirpyRed.Bool,
+		Handle:        updatedUser.Handle.String,
+		EmailVerified: updatedUser.EmailVerifiedAt.Valid,
+		PendingEmail:  pendingEmail,
 	}
 
 	// Set headers before writing response
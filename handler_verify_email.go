@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// handleVerifyEmail confirms the token from the verification email. the confirmation page posts
+// a form and gets a page back, api clients send json and get the user back
+func (cfg *apiConfig) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	fromPage := !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+
+	var token string
+	if fromPage {
+		token = r.PostFormValue("token")
+	} else {
+		type verifyEmailRequest struct {
+			Token string `json:"token"`
+		}
+		request := verifyEmailRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			log.Printf("error decoding email verification: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		token = request.Token
+	}
+
+	// errors are shown on the page for users who came from the email
+	writeError := func(status int, errResp errorResponse) {
+		if fromPage {
+			writeVerifyEmailPage(w, status, verifyEmailPageData{Error: errResp.Error})
+			return
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(errResp)
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
+
+	verification, err := qtx.UseEmailVerification(r.Context(), auth.HashToken(token))
+	if err == sql.ErrNoRows {
+		writeError(http.StatusBadRequest, errorResponse{Error: "Verification link is invalid or expired"})
+		return
+	}
+	if err != nil {
+		log.Printf("error using email verification: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// for an email change this is when the new address replaces the old one
+	verifiedUser, err := qtx.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		Email: verification.Email,
+		ID:    verification.UserID,
+	})
+	// someone else verified the same address first
+	if errResp, taken := takenFieldError(err); taken {
+		writeError(http.StatusConflict, errResp)
+		return
+	}
+	if err != nil {
+		log.Printf("error verifying email: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// verifying can change the email, the same way PUT /api/users does
+	if err := enqueueWebhookEvent(r.Context(), qtx, verifiedUser.ID, webhookUserUpdated, webhookUserFromDatabase(verifiedUser)); err != nil {
+		log.Printf("error enqueueing webhook event: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing email verification: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if fromPage {
+		writeVerifyEmailPage(w, http.StatusOK, verifyEmailPageData{Email: verifiedUser.Email})
+		return
+	}
+
+	userResponse := User{
+		ID:            verifiedUser.ID,
+		CreatedAt:     verifiedUser.CreatedAt,
+		UpdatedAt:     verifiedUser.UpdatedAt,
+		Email:         verifiedUser.Email,
+		IsChirpyRed:   verifiedUser.IsChirpyRed.Bool,
+		Handle:        verifiedUser.Handle.String,
+		EmailVerified: verifiedUser.EmailVerifiedAt.Valid,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(userResponse); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
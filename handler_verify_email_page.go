@@ -0,0 +1,62 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// the link in the email only opens this page, the address is verified when the form is posted.
+// link scanners and prefetchers open links without submitting forms, so they can't verify anything
+var verifyEmailPage = template.Must(template.New("verify-email").Parse(`<html>
+	<head>
+		<title>Verify your email - Chirpy</title>
+	</head>
+	<body>
+		{{if .Email}}
+		<h1>Your email is verified</h1>
+		<p>{{.Email}} is now the email of your Chirpy account.</p>
+		{{else if .Error}}
+		<h1>Can't verify your email</h1>
+		<p>{{.Error}}</p>
+		{{else}}
+		<h1>Verify your Chirpy email</h1>
+		<form method="POST" action="/api/verify-email">
+			<input type="hidden" name="token" value="{{.Token}}">
+			<button type="submit">Verify email</button>
+		</form>
+		{{end}}
+	</body>
+</html>
+`))
+
+type verifyEmailPageData struct {
+	Token string
+	Error string
+	// set once the email is verified
+	Email string
+}
+
+// handleVerifyEmailPage is opened from the link in the verification email
+func (cfg *apiConfig) handleVerifyEmailPage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeVerifyEmailPage(w, http.StatusBadRequest, verifyEmailPageData{Error: "Verification link is invalid or expired"})
+		return
+	}
+	writeVerifyEmailPage(w, http.StatusOK, verifyEmailPageData{Token: token})
+}
+
+func writeVerifyEmailPage(w http.ResponseWriter, status int, data verifyEmailPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// the page can't be framed, otherwise another site could trick users into clicking verify
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	// the token is in the url, it shouldn't leak to other sites through the referer
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := verifyEmailPage.Execute(w, data); err != nil {
+		log.Printf("error rendering verify email page: %v", err)
+	}
+}
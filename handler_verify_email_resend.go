@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/auth"
+)
+
+// handleVerifyEmailResend sends a new link for the email waiting to be verified
+func (cfg *apiConfig) handleVerifyEmailResend(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("error starting transaction: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.databaseQueries.WithTx(tx)
+
+	user, err := qtx.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// a pending email change takes priority over the current email
+	email := ""
+	latestVerification, err := qtx.GetLatestEmailVerification(r.Context(), userID)
+	if err == nil {
+		email = latestVerification.Email
+	} else if err != sql.ErrNoRows {
+		log.Printf("error getting email verification: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	} else if !user.EmailVerifiedAt.Valid {
+		email = user.Email
+	}
+	if email == "" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Email is already verified"})
+		return
+	}
+
+	verificationToken, err := startEmailVerification(r.Context(), qtx, userID, email)
+	if err != nil {
+		log.Printf("error creating email verification: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing email verification: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cfg.sendEmailVerification(email, verificationToken)
+
+	w.WriteHeader(http.StatusAccepted)
+}
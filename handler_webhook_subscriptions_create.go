@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handleWebhookSubscriptionsCreate(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	reqSubscription := WebhookSubscription{}
+	if err := decoder.Decode(&reqSubscription); err != nil {
+		log.Printf("error decoding webhook subscription: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := parseWebhookURL(reqSubscription.URL); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+
+	events := []string{}
+	seenEvents := map[string]bool{}
+	for _, event := range reqSubscription.Events {
+		if !outboundWebhookEvents[event] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorResponse{Error: fmt.Sprintf("Unknown event %q", event)})
+			return
+		}
+		if !seenEvents[event] {
+			seenEvents[event] = true
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse{Error: "Subscription needs at least one event"})
+		return
+	}
+
+	// deliveries are signed with the secret, the integrator uses it to check they came from chirpy
+	secret, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("error generating webhook secret: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	dbSubscription, err := cfg.databaseQueries.CreateWebhookSubscription(r.Context(), database.CreateWebhookSubscriptionParams{
+		UserID: userID,
+		Url:    reqSubscription.URL,
+		Secret: secret,
+		Events: events,
+	})
+	if err != nil {
+		log.Printf("error creating webhook subscription: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	subscription := webhookSubscriptionFromDatabase(dbSubscription)
+	subscription.Secret = secret
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(subscription); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
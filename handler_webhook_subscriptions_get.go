@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/troclaux/chirpy/internal/auth"
+)
+
+func (cfg *apiConfig) handleWebhookSubscriptionsGet(w http.ResponseWriter, r *http.Request) {
+
+	// check if request has authorization headers
+	jwtString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("error getting bearer token: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// validate jwt string
+	userID, err := cfg.jwtKeys.ValidateJWT(jwtString)
+	if err != nil {
+		log.Printf("error validating jwt: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	dbSubscriptions, err := cfg.databaseQueries.GetWebhookSubscriptions(r.Context(), userID)
+	if err != nil {
+		log.Printf("error getting webhook subscriptions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	subscriptions := []WebhookSubscription{}
+	for _, dbSubscription := range dbSubscriptions {
+		subscriptions = append(subscriptions, webhookSubscriptionFromDatabase(dbSubscription))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(subscriptions); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
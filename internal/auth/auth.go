@@ -2,14 +2,15 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -18,77 +19,18 @@ const (
 	TokenIssuer string = "issuer_is_chirpy"
 )
 
-func HashPassword(password string) (string, error) {
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-
-	return string(hashedPassword), nil
-}
-
-// compare password with hash string
-func CheckPasswordHash(password string, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-}
-
+// MakeJWT signs a token with a single HS256 secret, use a Keyring to rotate keys
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	// convert the secret to byte slice because cryptographic operations fundamentally work with bytes
-	// converting the string to bytes ensures consistent handling across different character encondings and platforms
-	signingKey := []byte(tokenSecret)
-	claims := jwt.RegisteredClaims{
-		Issuer:    TokenIssuer,
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(signingKey)
+	keyring := NewKeyring()
+	keyring.AddHMACKey(tokenSecret)
+	return keyring.MakeJWT(userID, expiresIn)
 }
 
 // Takes an encoded JWT token string and its secret key, returns the user's ID if valid
 func ValidateJWT(tokenString string, tokenSecret string) (uuid.UUID, error) {
-	// ParseWithClaims validates the token and extracts the claims
-	// what does it mean to validate the token and extract the claims?
-	// compare the calculated signature with the signature provided in the jwt
-	// check if the token is expired with the registered claim "expiration time"
-
-	// jwt.RegisteredClaims is a type (a struct).
-	// jwt.RegisteredClaims{} is a composite literal, which creates a new instance of the jwt.RegisteredClaims struct with default values.
-	// &jwt.RegisteredClaims{} is taking the address of the new instance created by the composite literal.
-	// input: encoded string, default claims and a key function that tells the parser how to validade the token's signature
-	// encoded string is header.payload.signature
-	// &jwt.RegisteredClaims{} is the struct that will store the decoded jwt
-	// output is the decoded validated token with claims, if there's any problems, return error
-	parsedToken, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// this anonymous function provides the secret key for signature validation
-		// convert string into a byte slice (because the jwt.ParseWithClaims requires a slice of bytes)
-		return []byte(tokenSecret), nil
-	})
-	if err != nil {
-		// Return an error if the token is invalid
-		return uuid.Nil, err
-	}
-
-	userIDString, err := parsedToken.Claims.GetSubject()
-	if err != nil {
-		return uuid.Nil, err
-	}
-
-	issuer, err := parsedToken.Claims.GetIssuer()
-	if err != nil {
-		return uuid.Nil, err
-	}
-	if issuer != TokenIssuer {
-		return uuid.Nil, errors.New("invalid issuer")
-	}
-
-	id, err := uuid.Parse(userIDString)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
-	}
-	return id, nil
+	keyring := NewKeyring()
+	keyring.AddHMACKey(tokenSecret)
+	return keyring.ValidateJWT(tokenString)
 }
 
 // bearer token is stored in the http request header's Authorization field
@@ -118,6 +60,84 @@ func MakeRefreshToken() (string, error) {
 	return token, nil
 }
 
+// PersonalAccessTokenPrefix tells personal access tokens apart from jwts in the Authorization header
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+func MakePersonalAccessToken() (string, error) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalAccessTokenPrefix + token, nil
+}
+
+// HashToken hashes a random token before it's stored, unlike passwords the token has enough entropy
+// that a fast hash is safe and lets the token be looked up by its hash
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// recovery codes use the base32 alphabet in lowercase so they're easy to read and type
+const recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// MakeRecoveryCode returns a random one-time code formatted as xxxxx-xxxxx
+func MakeRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, 11)
+	for i, randomByte := range b {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		// 256 is a multiple of 32 so every character is equally likely
+		code = append(code, recoveryCodeAlphabet[int(randomByte)%len(recoveryCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+// NormalizeRecoveryCode drops the formatting users might type so the code can be compared with its hash
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// recovery codes have about 50 random bits, so a low bcrypt cost is enough. they're kept off the
+// password hasher because a guess is checked against every unused code of the user
+const recoveryCodeCost = 6
+
+// HashRecoveryCode hashes a normalized recovery code before it's stored
+func HashRecoveryCode(code string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), recoveryCodeCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckRecoveryCode reports whether the normalized code matches the stored hash
+func CheckRecoveryCode(code string, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil
+}
+
+// VerifyCodeChallenge checks a pkce code verifier against the S256 challenge sent when authorizing (RFC 7636)
+func VerifyCodeChallenge(verifier string, challenge string) bool {
+	// verifiers are 43 to 128 unreserved characters
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, c := range verifier {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.ContainsRune("-._~", c)) {
+			return false
+		}
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	authHeader := headers.Get("Authorization")
 	if authHeader == "" {
@@ -2,10 +2,12 @@ package auth
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestCheckPasswordHash(t *testing.T) {
@@ -139,3 +141,74 @@ func TestGetAuthorizationToken(t *testing.T) {
 		t.Errorf("expected error message 'Authorization header not found', got '%s'", err.Error())
 	}
 }
+
+func TestMakeRecoveryCode(t *testing.T) {
+	code, err := MakeRecoveryCode()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(code) != 11 || code[5] != '-' {
+		t.Errorf("expected a code formatted as xxxxx-xxxxx, got '%s'", code)
+	}
+	if normalized := NormalizeRecoveryCode(" " + strings.ToUpper(code) + " "); normalized != strings.Replace(code, "-", "", 1) {
+		t.Errorf("expected normalized code to be '%s', got '%s'", strings.Replace(code, "-", "", 1), normalized)
+	}
+}
+
+func TestCheckRecoveryCode(t *testing.T) {
+	code := "abcdefghij"
+	hash, err := HashRecoveryCode(code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// codes made before they had their own hasher used the default bcrypt cost
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		hash string
+		want bool
+	}{
+		{name: "Correct code", code: code, hash: hash, want: true},
+		{name: "Wrong code", code: "abcdefghik", hash: hash, want: false},
+		{name: "Formatted code is normalized", code: NormalizeRecoveryCode("ABCDE-FGHIJ"), hash: hash, want: true},
+		{name: "Legacy hash", code: code, hash: string(legacyHash), want: true},
+		{name: "Invalid hash", code: code, hash: "not a hash", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckRecoveryCode(tt.code, tt.hash); got != tt.want {
+				t.Errorf("CheckRecoveryCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// the example from RFC 7636 appendix B
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name     string
+		verifier string
+		want     bool
+	}{
+		{name: "Matching verifier", verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", want: true},
+		{name: "Different verifier", verifier: "aBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", want: false},
+		{name: "Too short", verifier: "dBjftJeZ4CVP", want: false},
+		{name: "Invalid character", verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjX+", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyCodeChallenge(tt.verifier, challenge); got != tt.want {
+				t.Errorf("VerifyCodeChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
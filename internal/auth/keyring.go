@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// hmacKid is the kid of the shared secret, tokens signed with it have no kid header
+// so the ones issued before the keyring existed keep working
+const hmacKid = ""
+
+type signingKey struct {
+	method     jwt.SigningMethod
+	privateKey interface{}
+	publicKey  interface{}
+}
+
+// Keyring holds every key that is accepted when validating jwts, identified by their kid.
+// New tokens are signed with the active key, removing a key from the keyring retires it
+type Keyring struct {
+	activeKid string
+	keys      map[string]signingKey
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string]signingKey{}}
+}
+
+// AddHMACKey adds the shared HS256 secret, it's the active key until SetActive picks another one
+func (k *Keyring) AddHMACKey(secret string) {
+	k.keys[hmacKid] = signingKey{
+		method:     jwt.SigningMethodHS256,
+		privateKey: []byte(secret),
+		publicKey:  []byte(secret),
+	}
+}
+
+// AddKey adds an asymmetric key, ed25519 keys sign with EdDSA and rsa keys with RS256
+func (k *Keyring) AddKey(kid string, privateKey crypto.Signer) error {
+	if kid == hmacKid {
+		return errors.New("kid can't be empty")
+	}
+	if _, exists := k.keys[kid]; exists {
+		return fmt.Errorf("duplicate kid %q", kid)
+	}
+	switch key := privateKey.(type) {
+	case ed25519.PrivateKey:
+		k.keys[kid] = signingKey{method: jwt.SigningMethodEdDSA, privateKey: key, publicKey: key.Public()}
+	case *rsa.PrivateKey:
+		k.keys[kid] = signingKey{method: jwt.SigningMethodRS256, privateKey: key, publicKey: key.Public()}
+	default:
+		return fmt.Errorf("unsupported key type %T for kid %q", privateKey, kid)
+	}
+	return nil
+}
+
+// SetActive picks the key new tokens are signed with
+func (k *Keyring) SetActive(kid string) error {
+	if _, exists := k.keys[kid]; !exists {
+		return fmt.Errorf("no key with kid %q", kid)
+	}
+	k.activeKid = kid
+	return nil
+}
+
+// LoadKeyring reads every <kid>.pem private key in dir, dir and hmacSecret are both optional
+// but at least one key is needed. activeKid defaults to the hmac secret
+func LoadKeyring(dir string, activeKid string, hmacSecret string) (*Keyring, error) {
+	keyring := NewKeyring()
+	if hmacSecret != "" {
+		keyring.AddHMACKey(hmacSecret)
+	}
+
+	if dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			pemBytes, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			privateKey, err := ParsePrivateKey(pemBytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+			if err := keyring.AddKey(kid, privateKey); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(keyring.keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+	if err := keyring.SetActive(activeKid); err != nil {
+		return nil, fmt.Errorf("active key: %w", err)
+	}
+	return keyring, nil
+}
+
+// ParsePrivateKey decodes a PEM encoded PKCS #8 key, PKCS #1 is accepted for rsa keys
+func ParsePrivateKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", privateKey)
+	}
+	return signer, nil
+}
+
+// Claims are the claims of chirpy jwts, tokens issued to oauth clients are limited to their scope
+type Claims struct {
+	jwt.RegisteredClaims
+	// space separated like in oauth, empty on tokens with full access
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// ErrScopedToken is returned when a token limited to some scopes is used where full access is needed
+var ErrScopedToken = errors.New("token is limited to scopes")
+
+// MakeJWT returns a token with full access to the user's account
+func (k *Keyring) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.MakeScopedJWT(userID, "", nil, expiresIn)
+}
+
+// MakeScopedJWT returns a token for an oauth client that only allows the scopes
+func (k *Keyring) MakeScopedJWT(userID uuid.UUID, clientID string, scopes []string, expiresIn time.Duration) (string, error) {
+	key, exists := k.keys[k.activeKid]
+	if !exists {
+		return "", errors.New("no active signing key")
+	}
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Scope:    strings.Join(scopes, " "),
+		ClientID: clientID,
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	if k.activeKid != hmacKid {
+		token.Header["kid"] = k.activeKid
+	}
+	return token.SignedString(key.privateKey)
+}
+
+// ValidateJWT returns the user's ID if the token was signed by a key in the keyring and has full access
+func (k *Keyring) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	userID, scopes, err := k.ValidateScopedJWT(tokenString)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if scopes != nil {
+		return uuid.Nil, ErrScopedToken
+	}
+	return userID, nil
+}
+
+// ValidateScopedJWT returns the user's ID and the scopes the token is limited to, nil scopes mean full access
+func (k *Keyring) ValidateScopedJWT(tokenString string) (uuid.UUID, []string, error) {
+	// ParseWithClaims validates the token and extracts the claims
+	// what does it mean to validate the token and extract the claims?
+	// compare the calculated signature with the signature provided in the jwt
+	// check if the token is expired with the registered claim "expiration time"
+
+	// Claims embeds jwt.RegisteredClaims and adds the oauth scope
+	// &Claims{} is taking the address of a new instance created by the composite literal.
+	// input: encoded string, default claims and a key function that tells the parser how to validade the token's signature
+	// encoded string is header.payload.signature
+	// claims is the struct that will store the decoded jwt
+	// output is the decoded validated token with claims, if there's any problems, return error
+	claims := &Claims{}
+	parsedToken, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// this anonymous function picks the key for signature validation from the kid header
+		kid := hmacKid
+		if headerKid, exists := token.Header["kid"]; exists {
+			kidString, ok := headerKid.(string)
+			if !ok || kidString == hmacKid {
+				return nil, errors.New("invalid kid")
+			}
+			kid = kidString
+		}
+		key, exists := k.keys[kid]
+		if !exists {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		// the token can't pick its own algorithm, otherwise a public key could be used as an hmac secret
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.publicKey, nil
+	})
+	if err != nil {
+		// Return an error if the token is invalid
+		return uuid.Nil, nil, err
+	}
+
+	userIDString, err := parsedToken.Claims.GetSubject()
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	issuer, err := parsedToken.Claims.GetIssuer()
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	if issuer != TokenIssuer {
+		return uuid.Nil, nil, errors.New("invalid issuer")
+	}
+
+	id, err := uuid.Parse(userIDString)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	if claims.Scope == "" {
+		return id, nil, nil
+	}
+	return id, strings.Fields(claims.Scope), nil
+}
+
+// JWK is a public key in the JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// set on ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	// set on rsa keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys sorted by kid, the hmac secret is never published
+func (k *Keyring) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for kid, key := range k.keys {
+		switch publicKey := key.publicKey.(type) {
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: kid,
+				Alg: key.method.Alg(),
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: kid,
+				Alg: key.method.Alg(),
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		}
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+	return jwks
+}
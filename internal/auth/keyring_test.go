@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestKeyringValidateJWT(t *testing.T) {
+	userID := uuid.New()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	// tokens signed before the rotation
+	oldKeyring := NewKeyring()
+	oldKeyring.AddHMACKey("secret")
+	oldKeyring.AddKey("ed-1", edKey)
+	hmacToken, _ := oldKeyring.MakeJWT(userID, time.Hour)
+	oldKeyring.SetActive("ed-1")
+	edToken, _ := oldKeyring.MakeJWT(userID, time.Hour)
+
+	keyring := NewKeyring()
+	keyring.AddHMACKey("secret")
+	keyring.AddKey("ed-1", edKey)
+	keyring.AddKey("rsa-1", rsaKey)
+	keyring.SetActive("rsa-1")
+	rsaToken, _ := keyring.MakeJWT(userID, time.Hour)
+
+	// only the rsa key is left once the others are retired
+	retiredKeyring := NewKeyring()
+	retiredKeyring.AddKey("rsa-1", rsaKey)
+	retiredKeyring.SetActive("rsa-1")
+
+	// an hs256 token that uses the public key as its secret
+	confusedToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    TokenIssuer,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		Subject:   userID.String(),
+	})
+	confusedToken.Header["kid"] = "ed-1"
+	confusedTokenString, _ := confusedToken.SignedString([]byte(edKey.Public().(ed25519.PublicKey)))
+
+	tests := []struct {
+		name        string
+		keyring     *Keyring
+		tokenString string
+		wantErr     bool
+	}{
+		{
+			name:        "Active key",
+			keyring:     keyring,
+			tokenString: rsaToken,
+			wantErr:     false,
+		},
+		{
+			name:        "Previous asymmetric key",
+			keyring:     keyring,
+			tokenString: edToken,
+			wantErr:     false,
+		},
+		{
+			name:        "Token without kid",
+			keyring:     keyring,
+			tokenString: hmacToken,
+			wantErr:     false,
+		},
+		{
+			name:        "Retired asymmetric key",
+			keyring:     retiredKeyring,
+			tokenString: edToken,
+			wantErr:     true,
+		},
+		{
+			name:        "Retired hmac key",
+			keyring:     retiredKeyring,
+			tokenString: hmacToken,
+			wantErr:     true,
+		},
+		{
+			name:        "Algorithm doesn't match the kid",
+			keyring:     keyring,
+			tokenString: confusedTokenString,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID, err := tt.keyring.ValidateJWT(tt.tokenString)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && gotUserID != userID {
+				t.Errorf("ValidateJWT() gotUserID = %v, want %v", gotUserID, userID)
+			}
+		})
+	}
+}
+
+func TestKeyringJWKS(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	keyring := NewKeyring()
+	keyring.AddHMACKey("secret")
+	keyring.AddKey("b-ed", edKey)
+	keyring.AddKey("a-rsa", rsaKey)
+
+	jwks := keyring.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 public keys, got %d", len(jwks.Keys))
+	}
+	if jwks.Keys[0].Kid != "a-rsa" || jwks.Keys[0].Kty != "RSA" || jwks.Keys[0].Alg != "RS256" || jwks.Keys[0].E != "AQAB" {
+		t.Errorf("unexpected rsa key: %+v", jwks.Keys[0])
+	}
+	if jwks.Keys[1].Kid != "b-ed" || jwks.Keys[1].Kty != "OKP" || jwks.Keys[1].Alg != "EdDSA" || jwks.Keys[1].Crv != "Ed25519" {
+		t.Errorf("unexpected ed25519 key: %+v", jwks.Keys[1])
+	}
+}
+
+func TestKeyringScopedJWT(t *testing.T) {
+	userID := uuid.New()
+	keyring := NewKeyring()
+	keyring.AddHMACKey("secret")
+
+	scopedToken, _ := keyring.MakeScopedJWT(userID, "client", []string{"chirps:write", "profile:write"}, time.Hour)
+	fullToken, _ := keyring.MakeJWT(userID, time.Hour)
+
+	// scoped tokens can't be used where full access is needed
+	if _, err := keyring.ValidateJWT(scopedToken); err != ErrScopedToken {
+		t.Errorf("ValidateJWT() error = %v, want %v", err, ErrScopedToken)
+	}
+
+	gotUserID, scopes, err := keyring.ValidateScopedJWT(scopedToken)
+	if err != nil || gotUserID != userID || len(scopes) != 2 || scopes[0] != "chirps:write" || scopes[1] != "profile:write" {
+		t.Errorf("ValidateScopedJWT() = %v, %v, %v", gotUserID, scopes, err)
+	}
+
+	gotUserID, scopes, err = keyring.ValidateScopedJWT(fullToken)
+	if err != nil || gotUserID != userID || scopes != nil {
+		t.Errorf("ValidateScopedJWT() on a full access token = %v, %v, %v", gotUserID, scopes, err)
+	}
+}
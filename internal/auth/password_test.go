@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckPasswordHashFormats(t *testing.T) {
+	password := "correctPassword123!"
+	argon2Hash, _ := HashPassword(password)
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		wantErr  error
+	}{
+		{name: "Argon2id hash", password: password, hash: argon2Hash, wantErr: nil},
+		{name: "Wrong password for argon2id hash", password: "wrongPassword", hash: argon2Hash, wantErr: ErrPasswordMismatch},
+		{name: "Bcrypt hash", password: password, hash: string(bcryptHash), wantErr: nil},
+		{name: "Wrong password for bcrypt hash", password: "wrongPassword", hash: string(bcryptHash), wantErr: ErrPasswordMismatch},
+		{name: "Unset password", password: UnsetPasswordHash, hash: UnsetPasswordHash, wantErr: ErrPasswordUnset},
+		{name: "Empty hash", password: "", hash: "", wantErr: ErrPasswordUnset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckPasswordHash(tt.password, tt.hash); err != tt.wantErr {
+				t.Errorf("CheckPasswordHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	defer SetArgon2Params(DefaultArgon2Params)
+
+	currentHash, _ := HashPassword("password")
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
+	cheaper := DefaultArgon2Params
+	cheaper.Memory = 16 * 1024
+	if err := SetArgon2Params(cheaper); err != nil {
+		t.Fatalf("SetArgon2Params() error = %v", err)
+	}
+	cheaperHash, _ := HashPassword("password")
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{name: "Current parameters", hash: cheaperHash, want: false},
+		{name: "Outdated parameters", hash: currentHash, want: true},
+		{name: "Bcrypt hash", hash: string(bcryptHash), want: true},
+		{name: "Unset password", hash: UnsetPasswordHash, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !strings.HasPrefix(cheaperHash, "$argon2id$v=19$m=16384,t=3,p=2$") {
+		t.Errorf("HashPassword() = %q, want the argon2id PHC format", cheaperHash)
+	}
+}
+
+func TestSetArgon2Params(t *testing.T) {
+	defer SetArgon2Params(DefaultArgon2Params)
+
+	tests := []struct {
+		name    string
+		params  Argon2Params
+		wantErr bool
+	}{
+		{name: "Defaults", params: DefaultArgon2Params, wantErr: false},
+		{name: "No iterations", params: Argon2Params{Memory: 1024, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32}, wantErr: true},
+		{name: "Short salt", params: Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 32}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetArgon2Params(tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("SetArgon2Params() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package chirptext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// a tag or mention starts at the beginning of the text or after a character that can't be part of a word
+// so "email@example.com" and "c#" are not picked up
+var (
+	hashtagRegexp = regexp.MustCompile(`(?:^|[^A-Za-z0-9_])#([A-Za-z0-9_]+)`)
+	mentionRegexp = regexp.MustCompile(`(?:^|[^A-Za-z0-9_])@([A-Za-z0-9_]{1,15})\b`)
+	handleRegexp  = regexp.MustCompile(`^[A-Za-z0-9_]{1,15}$`)
+	// a hashtag or a mention, found the same way so censoring catches everything the others extract
+	entityRegexp = regexp.MustCompile(`(^|[^A-Za-z0-9_])[#@]([A-Za-z0-9_]+)`)
+)
+
+// Hashtags returns the lowercased hashtags in the text without the '#', in order of first appearance
+func Hashtags(text string) []string {
+	return extract(hashtagRegexp, text)
+}
+
+// Mentions returns the lowercased handles mentioned in the text without the '@', in order of first appearance
+func Mentions(text string) []string {
+	return extract(mentionRegexp, text)
+}
+
+// ValidHandle reports whether the handle can be mentioned, 1 to 15 letters, digits or underscores
+func ValidHandle(handle string) bool {
+	return handleRegexp.MatchString(handle)
+}
+
+// CensorEntities replaces every hashtag and mention whose word is banned with "****", punctuation
+// and whitespace around it are kept
+func CensorEntities(text string, banned func(word string) bool) string {
+	return entityRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		parts := entityRegexp.FindStringSubmatch(match)
+		if !banned(strings.ToLower(parts[2])) {
+			return match
+		}
+		return parts[1] + "****"
+	})
+}
+
+func extract(re *regexp.Regexp, text string) []string {
+	found := []string{}
+	seen := map[string]bool{}
+	for _, match := range re.FindAllStringSubmatch(text, -1) {
+		value := strings.ToLower(match[1])
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		found = append(found, value)
+	}
+	return found
+}
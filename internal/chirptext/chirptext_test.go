@@ -0,0 +1,133 @@
+package chirptext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "No hashtags",
+			text: "just a chirp",
+			want: []string{},
+		},
+		{
+			name: "Hashtags are lowercased and deduplicated",
+			text: "#Go is great #go #golang",
+			want: []string{"go", "golang"},
+		},
+		{
+			name: "Punctuation ends a hashtag",
+			text: "learning (#sql), #postgres!",
+			want: []string{"sql", "postgres"},
+		},
+		{
+			name: "Hash inside a word is not a hashtag",
+			text: "c# and issue#12",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hashtags(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hashtags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "Mentions are lowercased and deduplicated",
+			text: "hey @Alice and @bob, @alice",
+			want: []string{"alice", "bob"},
+		},
+		{
+			name: "Email addresses are not mentions",
+			text: "write to alice@example.com",
+			want: []string{},
+		},
+		{
+			name: "Handles longer than 15 characters are not mentions",
+			text: "@abcdefghijklmnopq",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	tests := []struct {
+		handle string
+		want   bool
+	}{
+		{handle: "alice_99", want: true},
+		{handle: "", want: false},
+		{handle: "with space", want: false},
+		{handle: "abcdefghijklmnop", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.handle, func(t *testing.T) {
+			if got := ValidHandle(tt.handle); got != tt.want {
+				t.Errorf("ValidHandle(%q) = %v, want %v", tt.handle, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCensorEntities(t *testing.T) {
+	banned := func(word string) bool {
+		return word == "kerfuffle" || word == "fornax"
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "Hashtag", text: "#kerfuffle", want: "****"},
+		{name: "Hashtag before punctuation", text: "what a #kerfuffle!", want: "what a ****!"},
+		{name: "Hashtag in parentheses", text: "(#Kerfuffle)", want: "(****)"},
+		{name: "Hashtag before a comma", text: "#kerfuffle, again", want: "****, again"},
+		{name: "Mention after a newline", text: "hi\n@fornax", want: "hi\n****"},
+		{name: "Hashtag after a newline", text: "hi\n#fornax", want: "hi\n****"},
+		{name: "Allowed entities are kept", text: "#go @alice #kerfuffles", want: "#go @alice #kerfuffles"},
+		{name: "Plain words are left alone", text: "kerfuffle c#fornax", want: "kerfuffle c#fornax"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CensorEntities(tt.text, banned)
+			if got != tt.want {
+				t.Errorf("CensorEntities() = %q, want %q", got, tt.want)
+			}
+			// nothing censored can be extracted afterwards
+			for _, entity := range append(Hashtags(got), Mentions(got)...) {
+				if banned(entity) {
+					t.Errorf("%q was extracted from %q", entity, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package entitlements defines what each subscription plan is allowed to do
+package entitlements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FreePlan is the plan of users without a subscription, every config has to define it
+const FreePlan = "free"
+
+// Limits are what a plan allows, a zero edit window, media count or scheduled_chirps turns the feature off
+type Limits struct {
+	MaxChirpLength int `json:"max_chirp_length"`
+	// how long after publishing a chirp can still be edited
+	EditWindow       Duration `json:"edit_window"`
+	ScheduledChirps  bool     `json:"scheduled_chirps"`
+	MaxChirpMedia    int      `json:"max_chirp_media"`
+	MaxChirpsPerHour int      `json:"max_chirps_per_hour"`
+}
+
+// Config maps plan names to their limits
+type Config map[string]Limits
+
+// Default is used when no config file is given
+func Default() Config {
+	return Config{
+		FreePlan: {
+			MaxChirpLength:   140,
+			MaxChirpMedia:    4,
+			MaxChirpsPerHour: 50,
+		},
+		"red": {
+			MaxChirpLength:   500,
+			EditWindow:       Duration(time.Hour),
+			ScheduledChirps:  true,
+			MaxChirpMedia:    10,
+			MaxChirpsPerHour: 300,
+		},
+	}
+}
+
+// Load reads a JSON config like {"free": {"max_chirp_length": 140, "edit_window": "0s", ...}, "red": {...}}
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := Config{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c Config) validate() error {
+	if _, exists := c[FreePlan]; !exists {
+		return fmt.Errorf("plan %q is missing", FreePlan)
+	}
+	for plan, limits := range c {
+		if limits.MaxChirpLength < 1 || limits.MaxChirpsPerHour < 1 {
+			return fmt.Errorf("plan %q: max_chirp_length and max_chirps_per_hour must be positive", plan)
+		}
+		if limits.EditWindow < 0 || limits.MaxChirpMedia < 0 {
+			return fmt.Errorf("plan %q: limits can't be negative", plan)
+		}
+	}
+	return nil
+}
+
+// For returns the limits of the plan, unknown plans get the free limits
+func (c Config) For(plan string) Limits {
+	if limits, exists := c[plan]; exists {
+		return limits
+	}
+	return c[FreePlan]
+}
+
+// Duration is a time.Duration written as a string like "15m" in the config
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.New("duration must be a string like \"1h\"")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
@@ -0,0 +1,66 @@
+package entitlements
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{
+			name:    "Valid config",
+			config:  `{"free": {"max_chirp_length": 140, "max_chirps_per_hour": 50}, "red": {"max_chirp_length": 500, "max_chirps_per_hour": 300, "edit_window": "15m", "scheduled_chirps": true}}`,
+			wantErr: false,
+		},
+		{name: "Missing free plan", config: `{"red": {"max_chirp_length": 500}}`, wantErr: true},
+		{name: "Invalid duration", config: `{"free": {"max_chirp_length": 140, "max_chirps_per_hour": 50, "edit_window": "soon"}}`, wantErr: true},
+		{name: "Duration without units", config: `{"free": {"max_chirp_length": 140, "max_chirps_per_hour": 50, "edit_window": 60}}`, wantErr: true},
+		{name: "No chirp length", config: `{"free": {"max_chirps_per_hour": 50}}`, wantErr: true},
+		{name: "No rate limit", config: `{"free": {"max_chirp_length": 140}}`, wantErr: true},
+		{name: "Negative limit", config: `{"free": {"max_chirp_length": 140, "max_chirps_per_hour": 50, "max_chirp_media": -1}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plans.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigFor(t *testing.T) {
+	config := Config{
+		FreePlan: {MaxChirpLength: 140},
+		"red":    {MaxChirpLength: 500, EditWindow: Duration(15 * time.Minute)},
+	}
+
+	tests := []struct {
+		name string
+		plan string
+		want Limits
+	}{
+		{name: "Free plan", plan: FreePlan, want: Limits{MaxChirpLength: 140}},
+		{name: "Red plan", plan: "red", want: Limits{MaxChirpLength: 500, EditWindow: Duration(15 * time.Minute)}},
+		{name: "Unknown plan", plan: "gold", want: Limits{MaxChirpLength: 140}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.For(tt.plan); got != tt.want {
+				t.Errorf("For() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+// Package lockout decides how long logins are blocked after repeated failures
+package lockout
+
+import "time"
+
+// Policy allows a few failures for free, then locks for a delay that doubles with every failure
+type Policy struct {
+	FreeFailures int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+}
+
+// Delay returns how long to lock after the given number of consecutive failures, 0 means no lock
+func (p Policy) Delay(failures int) time.Duration {
+	if failures <= p.FreeFailures {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeFailures + 1; i < failures; i++ {
+		delay *= 2
+		// checked every step so the delay can't overflow
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(delay, p.MaxDelay)
+}
@@ -0,0 +1,32 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{FreeFailures: 3, BaseDelay: time.Second, MaxDelay: time.Minute}
+
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{name: "No failures", failures: 0, want: 0},
+		{name: "Last free failure", failures: 3, want: 0},
+		{name: "First locked failure", failures: 4, want: time.Second},
+		{name: "Doubles", failures: 5, want: 2 * time.Second},
+		{name: "Doubles again", failures: 7, want: 8 * time.Second},
+		{name: "Capped", failures: 10, want: time.Minute},
+		{name: "Capped without overflowing", failures: 1000, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Delay(tt.failures); got != tt.want {
+				t.Errorf("Delay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// File writes every email to a .eml file in a directory instead of sending it, for local development
+type File struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir string, from string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("couldn't create mail directory: %w", err)
+	}
+	return &File{dir: dir, from: from}, nil
+}
+
+func (f *File) Send(ctx context.Context, message Message) error {
+	now := time.Now()
+	raw, err := message.format(f.from, now)
+	if err != nil {
+		return err
+	}
+
+	// the timestamp keeps the files sorted by when they were sent
+	file, err := os.CreateTemp(f.dir, now.UTC().Format("20060102T150405.000000000")+"-*.eml")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(raw); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails, implementations decide where they end up
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// format builds the raw RFC 5322 email
+func (m Message) format(from string, date time.Time) ([]byte, error) {
+	// a newline in a header would let the value add headers of its own
+	for _, header := range []string{from, m.To, m.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, errors.New("email headers can't contain newlines")
+		}
+	}
+
+	var raw bytes.Buffer
+	fmt.Fprintf(&raw, "From: %s\r\n", from)
+	fmt.Fprintf(&raw, "To: %s\r\n", m.To)
+	fmt.Fprintf(&raw, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&raw, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	raw.WriteString("MIME-Version: 1.0\r\n")
+	raw.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	raw.WriteString("\r\n")
+	raw.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return raw.Bytes(), nil
+}
@@ -0,0 +1,70 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSend(t *testing.T) {
+	dir := t.TempDir()
+	file, err := NewFile(dir, "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+	}
+
+	message := Message{To: "user@example.com", Subject: "Hello", Body: "first line\nsecond line"}
+	if err := file.Send(context.Background(), message); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(paths) != 1 {
+		t.Fatalf("expected 1 email file, got %d", len(paths))
+	}
+	raw, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatalf("reading email: %v", err)
+	}
+	for _, want := range []string{"From: chirpy@example.com\r\n", "To: user@example.com\r\n", "Subject: Hello\r\n", "\r\n\r\nfirst line\r\nsecond line"} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("expected email to contain %q, got %q", want, raw)
+		}
+	}
+}
+
+func TestSendRejectsHeaderInjection(t *testing.T) {
+	file, err := NewFile(t.TempDir(), "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		message Message
+	}{
+		{name: "Newline in recipient", message: Message{To: "user@example.com\r\nBcc: other@example.com", Subject: "Hello"}},
+		{name: "Newline in subject", message: Message{To: "user@example.com", Subject: "Hello\nBcc: other@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := file.Send(context.Background(), tt.message); err == nil {
+				t.Errorf("Send() expected an error")
+			}
+		})
+	}
+}
+
+func TestMemorySend(t *testing.T) {
+	memory := NewMemory()
+	memory.Send(context.Background(), Message{To: "a@example.com"})
+	memory.Send(context.Background(), Message{To: "b@example.com"})
+
+	messages := memory.Messages()
+	if len(messages) != 2 || messages[0].To != "a@example.com" || messages[1].To != "b@example.com" {
+		t.Errorf("unexpected messages: %+v", messages)
+	}
+}
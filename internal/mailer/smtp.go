@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP sends emails through an SMTP server, STARTTLS is used when the server supports it
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTP returns a mailer for host:port, username and password are optional
+func NewSMTP(host string, port string, username string, password string, from string) *SMTP {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTP{addr: net.JoinHostPort(host, port), auth: auth, from: from}
+}
+
+func (s *SMTP) Send(ctx context.Context, message Message) error {
+	raw, err := message.format(s.from, time.Now())
+	if err != nil {
+		return err
+	}
+	// net/smtp doesn't take a context, the server's own timeouts apply
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{message.To}, raw)
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalPutOpen(t *testing.T) {
+	local, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if err := local.Put(ctx, "abc123", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	// putting the same key again keeps the existing blob
+	if err := local.Put(ctx, "abc123", strings.NewReader("hello")); err != nil {
+		t.Fatalf("second Put() error = %v", err)
+	}
+
+	blob, err := local.Open(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer blob.Close()
+	content, err := io.ReadAll(blob)
+	if err != nil {
+		t.Fatalf("reading blob: %v", err)
+	}
+	if string(content) != "hello" {
+		t.Errorf("expected content to be 'hello', got '%s'", content)
+	}
+}
+
+func TestLocalOpenMissing(t *testing.T) {
+	local, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal() error = %v", err)
+	}
+	_, err = local.Open(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLocalInvalidKeys(t *testing.T) {
+	local, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal() error = %v", err)
+	}
+	for _, key := range []string{"", "..", "../escape", "nested/key", ".hidden"} {
+		t.Run(key, func(t *testing.T) {
+			if err := local.Put(context.Background(), key, strings.NewReader("x")); err == nil {
+				t.Errorf("expected error for key %q, got nil", key)
+			}
+		})
+	}
+}
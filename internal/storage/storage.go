@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when there's no blob stored under a key
+var ErrNotFound = errors.New("blob not found")
+
+// Storage keeps uploaded blobs, keys are chosen by the caller
+type Storage interface {
+	// Put stores the content under the key, overwriting is not needed because keys are content addresses
+	Put(ctx context.Context, key string, content io.Reader) error
+	// Open returns the content stored under the key, the caller must close it
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+}
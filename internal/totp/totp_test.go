@@ -0,0 +1,82 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the sha1 test vectors from RFC 6238 appendix B, truncated to 6 digits
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{name: "59", unix: 59, want: "287082"},
+		{name: "1111111109", unix: 1111111109, want: "081804"},
+		{name: "1111111111", unix: 1111111111, want: "050471"},
+		{name: "1234567890", unix: 1234567890, want: "005924"},
+		{name: "2000000000", unix: 2000000000, want: "279037"},
+		{name: "20000000000", unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatalf("Code() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Code() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name   string
+		codeAt time.Time
+		code   string
+		wantOK bool
+	}{
+		{name: "Current step", codeAt: now, wantOK: true},
+		{name: "Previous step", codeAt: now.Add(-Period), wantOK: true},
+		{name: "Next step", codeAt: now.Add(Period), wantOK: true},
+		{name: "Too old", codeAt: now.Add(-2 * Period), wantOK: false},
+		{name: "Wrong code", code: "000000", wantOK: false},
+		{name: "Too short", code: "12345", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := tt.code
+			if code == "" {
+				code, _ = Code(rfcSecret, Step(tt.codeAt))
+			}
+			step, ok := Validate(rfcSecret, code, now)
+			if ok != tt.wantOK {
+				t.Errorf("Validate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != Step(tt.codeAt) {
+				t.Errorf("Validate() step = %v, want %v", step, Step(tt.codeAt))
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Chirpy", "user@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:user@example.com?") {
+		t.Errorf("unexpected uri prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=Chirpy") {
+		t.Errorf("uri is missing the secret or issuer: %s", uri)
+	}
+}
@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		want bool
+	}{
+		{name: "Public IPv4", addr: "93.184.216.34", want: true},
+		{name: "Public IPv6", addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{name: "Loopback", addr: "127.0.0.1", want: false},
+		{name: "IPv6 loopback", addr: "::1", want: false},
+		{name: "Private network", addr: "10.1.2.3", want: false},
+		{name: "Link local metadata service", addr: "169.254.169.254", want: false},
+		{name: "Carrier-grade NAT", addr: "100.64.0.1", want: false},
+		{name: "IPv4-mapped private", addr: "::ffff:192.168.0.1", want: false},
+		{name: "Unspecified", addr: "0.0.0.0", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package webhook
+
+import "time"
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it's dead-lettered
+	MaxAttempts    = 10
+	baseRetryDelay = 30 * time.Second
+	maxRetryDelay  = 6 * time.Hour
+)
+
+// RetryDelay returns how long to wait after the given failed attempt, it doubles from 30s up to 6h
+func RetryDelay(attempt int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		want    time.Duration
+	}{
+		{name: "First attempt", attempt: 1, want: 30 * time.Second},
+		{name: "Doubles", attempt: 2, want: time.Minute},
+		{name: "Doubles again", attempt: 4, want: 4 * time.Minute},
+		{name: "Capped", attempt: 20, want: 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryDelay(tt.attempt); got != tt.want {
+				t.Errorf("RetryDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
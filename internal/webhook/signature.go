@@ -0,0 +1,73 @@
+// Package webhook signs and verifies webhook bodies with HMAC-SHA256
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidHeader     = errors.New("invalid signature header")
+	ErrTimestampTooOld   = errors.New("signature timestamp outside the tolerance")
+	ErrSignatureMismatch = errors.New("signature doesn't match")
+)
+
+// Sign returns the signature header for the body, "t=<unix timestamp>,v1=<hex hmac>".
+// the timestamp is signed with the body so an old delivery can't be replayed with a new timestamp
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + computeSignature(secret, unix, body)
+}
+
+// Verify checks the signature header against the body, the timestamp has to be within tolerance of now.
+// there can be several v1 signatures so the sender can rotate its secret
+func Verify(secret string, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	unix := ""
+	signatures := []string{}
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			return ErrInvalidHeader
+		}
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if unix == "" || len(signatures) == 0 {
+		return ErrInvalidHeader
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return ErrInvalidHeader
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrTimestampTooOld
+	}
+
+	expected := computeSignature(secret, unix, body)
+	for _, signature := range signatures {
+		// hmac.Equal takes the same time wherever the signatures differ
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+func computeSignature(secret string, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret := "polka-secret"
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := Sign(secret, signedAt, body)
+	// signed with the old and the new secret while the secret is being rotated
+	rotatedHeader := Sign("old-secret", signedAt, body) + ",v1=" + computeSignature(secret, "1700000000", body)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{name: "Valid signature", secret: secret, header: header, body: body, now: signedAt.Add(time.Minute), wantErr: nil},
+		{name: "Rotated secret", secret: secret, header: rotatedHeader, body: body, now: signedAt, wantErr: nil},
+		{name: "Wrong secret", secret: "other-secret", header: header, body: body, now: signedAt, wantErr: ErrSignatureMismatch},
+		{name: "Modified body", secret: secret, header: header, body: []byte(`{"id":"evt_2","event":"user.upgraded"}`), now: signedAt, wantErr: ErrSignatureMismatch},
+		{name: "Replayed too late", secret: secret, header: header, body: body, now: signedAt.Add(10 * time.Minute), wantErr: ErrTimestampTooOld},
+		{name: "Timestamp in the future", secret: secret, header: header, body: body, now: signedAt.Add(-10 * time.Minute), wantErr: ErrTimestampTooOld},
+		{name: "Missing timestamp", secret: secret, header: "v1=abc", body: body, now: signedAt, wantErr: ErrInvalidHeader},
+		{name: "Empty header", secret: secret, header: "", body: body, now: signedAt, wantErr: ErrInvalidHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.header, tt.body, tt.now, 5*time.Minute); err != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
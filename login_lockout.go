@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/lockout"
+)
+
+// failures are forgotten after a quiet hour
+const loginFailureWindow = time.Hour
+
+var (
+	// emails are tracked whether or not they have an account, so lockouts don't reveal which ones exist
+	emailLoginPolicy = lockout.Policy{FreeFailures: 5, BaseDelay: time.Second, MaxDelay: 15 * time.Minute}
+	// more lenient because many users can share an address behind a NAT
+	ipLoginPolicy = lockout.Policy{FreeFailures: 20, BaseDelay: time.Second, MaxDelay: 15 * time.Minute}
+)
+
+// compared against when the email has no account, so unknown emails take as long as wrong passwords.
+// it's made on first use so it has the argon2 costs set at startup
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := auth.HashPassword("chirpy-dummy-password")
+	return hash
+})
+
+var errIncorrectLogin = errors.New("Incorrect email or password")
+
+// loginLockedError is returned while the email or the ip address is locked out
+type loginLockedError struct {
+	lockedUntil time.Time
+}
+
+func (e *loginLockedError) Error() string {
+	return "Too many failed login attempts, try again later"
+}
+
+// retryAfter is the value of the Retry-After header, in seconds
+func (e *loginLockedError) retryAfter() string {
+	seconds := int(math.Ceil(time.Until(e.lockedUntil).Seconds()))
+	return strconv.Itoa(max(seconds, 1))
+}
+
+// checkPassword returns the user if the password is correct, failures count towards locking
+// the email and the ip address out. unknown emails fail the same way wrong passwords do
+func (cfg *apiConfig) checkPassword(ctx context.Context, email string, password string, ip string) (database.User, error) {
+	// locked emails and addresses are refused before the password is checked
+	emailKey := emailLoginKey(email)
+	ipKey := ipLoginKey(ip)
+	lockedUntil, err := cfg.loginLockedUntil(ctx, emailKey, ipKey)
+	if err != nil {
+		return database.User{}, err
+	}
+	if !lockedUntil.IsZero() {
+		return database.User{}, &loginLockedError{lockedUntil: lockedUntil}
+	}
+
+	potentialUser, err := cfg.databaseQueries.AuthenticateUser(ctx, email)
+	userFound := true
+	hashedPassword := potentialUser.HashedPassword
+	if err == sql.ErrNoRows {
+		userFound = false
+		hashedPassword = dummyPasswordHash()
+	} else if err != nil {
+		return database.User{}, err
+	} else if hashedPassword == auth.UnsetPasswordHash {
+		// accounts from before passwords existed can't log in until they reset it,
+		// they fail like unknown emails so the response doesn't reveal them
+		log.Printf("login attempt for user %v without a password", potentialUser.ID)
+		userFound = false
+		hashedPassword = dummyPasswordHash()
+	}
+
+	// compare request password with database password
+	if err := auth.CheckPasswordHash(password, hashedPassword); err != nil || !userFound {
+		cfg.recordLoginFailures(ctx, emailKey, ipKey)
+		return database.User{}, errIncorrectLogin
+	}
+
+	// bcrypt hashes and argon2id hashes with old costs are replaced now that the password is known
+	if auth.NeedsRehash(hashedPassword) {
+		cfg.rehashPassword(ctx, potentialUser, password)
+	}
+	return potentialUser, nil
+}
+
+// rehashPassword hashes the password with the current algorithm, failures are only logged
+// since the old hash keeps working
+func (cfg *apiConfig) rehashPassword(ctx context.Context, user database.User, password string) {
+	newHash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("error rehashing password: %v", err)
+		return
+	}
+	// the old hash is checked again so a password changed in the meantime isn't overwritten
+	err = cfg.databaseQueries.RehashUserPassword(ctx, database.RehashUserPasswordParams{
+		NewHash: newHash,
+		ID:      user.ID,
+		OldHash: user.HashedPassword,
+	})
+	if err != nil {
+		log.Printf("error updating rehashed password: %v", err)
+	}
+}
+
+func emailLoginKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginLockedUntil returns the latest lockout among the keys, the zero time when none is locked
+func (cfg *apiConfig) loginLockedUntil(ctx context.Context, keys ...string) (time.Time, error) {
+	lockouts, err := cfg.databaseQueries.GetLoginLockouts(ctx, keys)
+	if err != nil {
+		return time.Time{}, err
+	}
+	lockedUntil := time.Time{}
+	for _, loginLockout := range lockouts {
+		if loginLockout.LockedUntil.Time.After(lockedUntil) {
+			lockedUntil = loginLockout.LockedUntil.Time
+		}
+	}
+	return lockedUntil, nil
+}
+
+// recordLoginFailures counts a wrong password or code against both the email and the ip address
+func (cfg *apiConfig) recordLoginFailures(ctx context.Context, emailKey string, ipKey string) {
+	if err := cfg.recordLoginFailure(ctx, emailKey, emailLoginPolicy); err != nil {
+		log.Printf("error recording login failure: %v", err)
+	}
+	if err := cfg.recordLoginFailure(ctx, ipKey, ipLoginPolicy); err != nil {
+		log.Printf("error recording login failure: %v", err)
+	}
+}
+
+// recordLoginFailure counts the failure and locks the key once the policy says so
+func (cfg *apiConfig) recordLoginFailure(ctx context.Context, key string, policy lockout.Policy) error {
+	failure, err := cfg.databaseQueries.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key:         key,
+		ResetBefore: time.Now().Add(-loginFailureWindow),
+	})
+	if err != nil {
+		return err
+	}
+	delay := policy.Delay(int(failure.Failures))
+	if delay == 0 {
+		return nil
+	}
+	return cfg.databaseQueries.LockLogin(ctx, database.LockLoginParams{
+		LockedUntil: sql.NullTime{Time: time.Now().Add(delay), Valid: true},
+		Key:         key,
+	})
+}
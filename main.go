@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/troclaux/chirpy/internal/auth"
 	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/entitlements"
+	"github.com/troclaux/chirpy/internal/mailer"
+	"github.com/troclaux/chirpy/internal/storage"
+	"github.com/troclaux/chirpy/internal/webhook"
 
 	_ "github.com/lib/pq"
 )
@@ -17,10 +26,17 @@ import (
 type apiConfig struct {
 	// atomic.Int32 is a type that provides atomic operations for int32 values
 	fileserverHits  atomic.Int32
+	db              *sql.DB
 	databaseQueries *database.Queries
 	platform        string
-	jwtSecret       string
+	jwtKeys         *auth.Keyring
 	polkaKey        string
+	adminKey        string
+	mediaStorage    storage.Storage
+	mailer          mailer.Mailer
+	appURL          string
+	plans           entitlements.Config
+	webhookClient   *http.Client
 }
 
 // middlewareMetricsInc increments the fileserverHits counter for each request
@@ -71,6 +87,9 @@ type errorResponse struct {
 	Error string `json:"error"`
 }
 
+// uniqueViolation is the postgres error code for a unique constraint violation
+const uniqueViolation = "23505"
+
 func main() {
 
 	// load env vars from .env file
@@ -104,22 +123,111 @@ func main() {
 		log.Fatal("SIGNING_KEY environment variable is not set")
 	}
 
+	// jwts are signed with the key named by JWT_ACTIVE_KID, every <kid>.pem in JWT_KEYS_DIR is accepted.
+	// the SIGNING_KEY hs256 secret is optional once JWT_KEYS_DIR is set, unset it to retire it
 	var signingKey string = os.Getenv("SIGNING_KEY")
-	if signingKey == "" {
+	jwtKeysDir := os.Getenv("JWT_KEYS_DIR")
+	if signingKey == "" && jwtKeysDir == "" {
 		log.Fatal("SIGNING_KEY environment variable is not set")
 	}
+	jwtKeys, err := auth.LoadKeyring(jwtKeysDir, os.Getenv("JWT_ACTIVE_KID"), signingKey)
+	if err != nil {
+		log.Fatalf("Error loading jwt signing keys: %v", err)
+	}
+
+	// passwords are hashed with argon2id, ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and ARGON2_PARALLELISM
+	// override the default costs. existing hashes are upgraded when their users log in
+	argon2Params := auth.DefaultArgon2Params
+	for name, param := range map[string]*uint32{"ARGON2_MEMORY_KIB": &argon2Params.Memory, "ARGON2_ITERATIONS": &argon2Params.Iterations} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				log.Fatalf("Error parsing %s: %v", name, err)
+			}
+			*param = uint32(parsed)
+		}
+	}
+	if value := os.Getenv("ARGON2_PARALLELISM"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			log.Fatalf("Error parsing ARGON2_PARALLELISM: %v", err)
+		}
+		argon2Params.Parallelism = uint8(parsed)
+	}
+	if err := auth.SetArgon2Params(argon2Params); err != nil {
+		log.Fatalf("Error setting argon2 parameters: %v", err)
+	}
 
+	// polka signs its webhooks with POLKA_KEY, the key itself is never sent
 	var polkaKey string = os.Getenv("POLKA_KEY")
 	if polkaKey == "" {
 		log.Fatal("POLKA_KEY environment variable is not set")
 	}
 
+	// admin endpoints are disabled when ADMIN_KEY isn't set
+	adminKey := os.Getenv("ADMIN_KEY")
+
+	// uploaded media is kept on the local disk, MEDIA_DIR is optional
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	mediaStorage, err := storage.NewLocal(mediaDir)
+	if err != nil {
+		log.Fatalf("Error creating media storage: %v", err)
+	}
+
+	// emails go through SMTP_HOST when it's set, otherwise they're written to MAIL_DIR for local development
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "chirpy@localhost"
+	}
+	var appMailer mailer.Mailer
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpPort := os.Getenv("SMTP_PORT")
+		if smtpPort == "" {
+			smtpPort = "587"
+		}
+		appMailer = mailer.NewSMTP(smtpHost, smtpPort, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), mailFrom)
+	} else {
+		mailDir := os.Getenv("MAIL_DIR")
+		if mailDir == "" {
+			mailDir = "mail"
+		}
+		appMailer, err = mailer.NewFile(mailDir, mailFrom)
+		if err != nil {
+			log.Fatalf("Error creating mail directory: %v", err)
+		}
+	}
+
+	// links in emails point to APP_URL
+	appURL := strings.TrimSuffix(os.Getenv("APP_URL"), "/")
+	if appURL == "" {
+		appURL = "http://localhost:8080"
+	}
+
+	// what each plan allows comes from PLANS_FILE, the built-in plans are used when it isn't set
+	plans := entitlements.Default()
+	if plansFile := os.Getenv("PLANS_FILE"); plansFile != "" {
+		plans, err = entitlements.Load(plansFile)
+		if err != nil {
+			log.Fatalf("Error loading plans: %v", err)
+		}
+	}
+
 	// initialize struct with request counter and connection pool
 	apiCfg := &apiConfig{
+		db:              db,
 		databaseQueries: dbQueries,
 		platform:        platform,
-		jwtSecret:       signingKey,
+		jwtKeys:         jwtKeys,
 		polkaKey:        polkaKey,
+		adminKey:        adminKey,
+		mediaStorage:    mediaStorage,
+		mailer:          appMailer,
+		appURL:          appURL,
+		plans:           plans,
+		webhookClient:   webhook.NewClient(webhookDeliveryTimeout),
 	}
 
 	// serves files to the client from the defined path
@@ -130,18 +238,71 @@ func main() {
 
 	mux.HandleFunc("POST /admin/reset", apiCfg.handleReset)
 	mux.HandleFunc("GET /admin/metrics", apiCfg.handleMetrics)
+	mux.HandleFunc("GET /admin/lockouts", apiCfg.handleAdminLockoutsGet)
+	mux.HandleFunc("GET /admin/webhooks/deliveries", apiCfg.handleAdminWebhookDeliveriesGet)
+	mux.HandleFunc("POST /admin/webhooks/deliveries/{deliveryID}/replay", apiCfg.handleAdminWebhookDeliveryReplay)
 	mux.HandleFunc("POST /api/users", apiCfg.handleUsersCreate)
 	mux.HandleFunc("PUT /api/users", apiCfg.handleUsersUpdate)
+	mux.HandleFunc("GET /api/users/me/mentions", apiCfg.handleMentionsGet)
+	mux.HandleFunc("GET /api/users/me/entitlements", apiCfg.handleEntitlementsGet)
+	mux.HandleFunc("POST /api/users/me/verify-email", apiCfg.handleVerifyEmailResend)
+	mux.HandleFunc("POST /api/users/me/2fa", apiCfg.handleTwoFactorCreate)
+	mux.HandleFunc("POST /api/users/me/2fa/confirm", apiCfg.handleTwoFactorConfirm)
+	mux.HandleFunc("DELETE /api/users/me/2fa", apiCfg.handleTwoFactorDelete)
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.handleFollowCreate)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.handleFollowDelete)
 	mux.HandleFunc("POST /api/login", apiCfg.handleLogin)
+	mux.HandleFunc("POST /api/login/2fa", apiCfg.handleLoginTwoFactor)
+	mux.HandleFunc("POST /api/password-reset/request", apiCfg.handlePasswordResetRequest)
+	mux.HandleFunc("POST /api/password-reset/confirm", apiCfg.handlePasswordResetConfirm)
+	mux.HandleFunc("GET /api/verify-email", apiCfg.handleVerifyEmailPage)
+	mux.HandleFunc("POST /api/verify-email", apiCfg.handleVerifyEmail)
 	mux.HandleFunc("GET /api/healthz", handleReadiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.handleJWKSGet)
 	mux.HandleFunc("POST /api/chirps", apiCfg.handleCreateChirps)
 	mux.HandleFunc("GET /api/chirps", apiCfg.handleChirpsGet)
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.handleChirpsSearch)
+	mux.HandleFunc("GET /api/chirps/scheduled", apiCfg.handleChirpsScheduledGet)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handleChirpGet)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.handleChirpUpdate)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handleChirpDelete)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.handleChirpRevisionsGet)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.handleChirpThreadGet)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.handleChirpLikeCreate)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.handleChirpLikeDelete)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/schedule", apiCfg.handleChirpScheduleDelete)
+	mux.HandleFunc("POST /api/media", apiCfg.handleMediaCreate)
+	mux.HandleFunc("GET /media/{sha256}", apiCfg.handleMediaGet)
+	mux.HandleFunc("GET /api/timeline", apiCfg.handleTimelineGet)
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", apiCfg.handleTagChirpsGet)
 	mux.HandleFunc("POST /api/refresh", apiCfg.handleRefreshToken)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handleTokenRevocation)
+	mux.HandleFunc("GET /api/sessions", apiCfg.handleSessionsGet)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.handleSessionDelete)
+	mux.HandleFunc("POST /api/sessions/revoke-all", apiCfg.handleSessionsRevokeAll)
+	mux.HandleFunc("POST /api/tokens", apiCfg.handleTokensCreate)
+	mux.HandleFunc("GET /api/tokens", apiCfg.handleTokensGet)
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.handleTokenDelete)
+	mux.HandleFunc("POST /api/oauth/clients", apiCfg.handleOAuthClientsCreate)
+	mux.HandleFunc("GET /api/oauth/clients", apiCfg.handleOAuthClientsGet)
+	mux.HandleFunc("DELETE /api/oauth/clients/{clientID}", apiCfg.handleOAuthClientDelete)
+	mux.HandleFunc("GET /oauth/authorize", apiCfg.handleOAuthAuthorizeGet)
+	mux.HandleFunc("POST /oauth/authorize", apiCfg.handleOAuthAuthorizePost)
+	mux.HandleFunc("POST /oauth/token", apiCfg.handleOAuthToken)
+	mux.HandleFunc("POST /api/webhooks/subscriptions", apiCfg.handleWebhookSubscriptionsCreate)
+	mux.HandleFunc("GET /api/webhooks/subscriptions", apiCfg.handleWebhookSubscriptionsGet)
+	mux.HandleFunc("DELETE /api/webhooks/subscriptions/{subscriptionID}", apiCfg.handleWebhookSubscriptionDelete)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handleEventWebhook)
 
+	// publish scheduled chirps in the background
+	go apiCfg.runChirpPublisher(context.Background(), 15*time.Second)
+	// take chirpy red away from lapsed subscriptions
+	go apiCfg.runSubscriptionExpirer(context.Background(), time.Minute)
+	// deliver outbound webhooks from the outbox
+	go apiCfg.runWebhookDispatcher(context.Background(), 5*time.Second)
+	// forget old webhook deliveries
+	go apiCfg.runWebhookDeliveryPruner(context.Background(), time.Hour)
+
 	fmt.Println("Server is running on http://localhost:8080")
 
 	if err := http.ListenAndServe(":8080", mux); err != nil {
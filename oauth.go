@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+const (
+	// codes are exchanged right after the redirect, so they don't need to live long
+	authorizationCodeDuration = 10 * time.Minute
+	oauthAccessTokenDuration  = time.Hour
+)
+
+// oauthScopeDescriptions are shown on the consent page, clients can ask for the same scopes as personal access tokens
+var oauthScopeDescriptions = map[string]string{
+	scopeChirpsWrite:  "Post chirps as you",
+	scopeChirpsDelete: "Delete your chirps",
+	scopeProfileWrite: "Change your profile",
+}
+
+// OAuthClient is a third-party app, the secret is only returned when a confidential client is registered
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+	Secret       string    `json:"client_secret,omitempty"`
+}
+
+func oauthClientFromDatabase(dbClient database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           dbClient.ID,
+		Name:         dbClient.Name,
+		RedirectURIs: dbClient.RedirectUris,
+		Confidential: dbClient.SecretHash.Valid,
+		CreatedAt:    dbClient.CreatedAt,
+	}
+}
+
+// parseRedirectURI only accepts https urls, plain http is allowed for apps running on the user's machine
+func parseRedirectURI(redirectURI string) error {
+	parsedURI, err := url.Parse(redirectURI)
+	if err != nil || !parsedURI.IsAbs() || parsedURI.Host == "" {
+		return fmt.Errorf("Redirect URI %q must be an absolute URL", redirectURI)
+	}
+	if parsedURI.Fragment != "" {
+		return fmt.Errorf("Redirect URI %q can't have a fragment", redirectURI)
+	}
+	loopback := parsedURI.Hostname() == "localhost" || parsedURI.Hostname() == "127.0.0.1" || parsedURI.Hostname() == "::1"
+	if parsedURI.Scheme != "https" && !(parsedURI.Scheme == "http" && loopback) {
+		return fmt.Errorf("Redirect URI %q must use https", redirectURI)
+	}
+	return nil
+}
+
+// parseOAuthScopes splits the space separated scope parameter, at least one known scope is needed
+func parseOAuthScopes(scope string) ([]string, error) {
+	scopes := []string{}
+	for _, s := range strings.Fields(scope) {
+		if _, known := oauthScopeDescriptions[s]; !known {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is needed")
+	}
+	return scopes, nil
+}
+
+// oauthRedirect appends params to the client's redirect uri, keeping the query it already has
+func oauthRedirect(redirectURI string, params url.Values) string {
+	parsedURI, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := parsedURI.Query()
+	for key, values := range params {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+	parsedURI.RawQuery = query.Encode()
+	return parsedURI.String()
+}
+
+// oauthErrorResponse is the error format of the token endpoint (RFC 6749 section 5.2)
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+	}
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(oauthErrorResponse{Error: code, ErrorDescription: description}); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/webhook"
+)
+
+// events integrators can subscribe to, each subscription only gets the events of its owner's account
+const (
+	webhookChirpCreated = "chirp.created"
+	webhookChirpDeleted = "chirp.deleted"
+	webhookUserUpdated  = "user.updated"
+)
+
+var outboundWebhookEvents = map[string]bool{
+	webhookChirpCreated: true,
+	webhookChirpDeleted: true,
+	webhookUserUpdated:  true,
+}
+
+const (
+	// webhookBatchSize is how many due deliveries one instance claims at a time
+	webhookBatchSize       = 20
+	webhookDeliveryTimeout = 10 * time.Second
+	chirpySignatureHeader  = "X-Chirpy-Signature"
+)
+
+// WebhookSubscription is an endpoint registered by an integrator, the secret is only returned when it's created
+type WebhookSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+func webhookSubscriptionFromDatabase(dbSubscription database.WebhookSubscription) WebhookSubscription {
+	return WebhookSubscription{
+		ID:        dbSubscription.ID,
+		URL:       dbSubscription.Url,
+		Events:    dbSubscription.Events,
+		CreatedAt: dbSubscription.CreatedAt,
+	}
+}
+
+// parseWebhookURL only accepts https urls, private addresses are refused when delivering
+func parseWebhookURL(webhookURL string) error {
+	parsedURL, err := url.Parse(webhookURL)
+	if err != nil || parsedURL.Scheme != "https" || parsedURL.Host == "" {
+		return fmt.Errorf("Webhook URL %q must be an absolute https URL", webhookURL)
+	}
+	if parsedURL.User != nil {
+		return fmt.Errorf("Webhook URL %q can't have credentials", webhookURL)
+	}
+	return nil
+}
+
+// webhookUser is the data of user.updated, without the password hash
+type webhookUser struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Handle        string    `json:"handle,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+}
+
+func webhookUserFromDatabase(user database.User) webhookUser {
+	return webhookUser{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		IsChirpyRed:   user.IsChirpyRed.Bool,
+		Handle:        user.Handle.String,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+	}
+}
+
+// enqueueWebhookEvent writes the event to the outbox for every subscription of the user that wants it.
+// qtx should be the transaction of the change, so the event is only sent if the change is committed
+func enqueueWebhookEvent(ctx context.Context, qtx *database.Queries, userID uuid.UUID, eventType string, data any) error {
+	type webhookPayload struct {
+		ID        uuid.UUID `json:"id"`
+		Type      string    `json:"type"`
+		CreatedAt time.Time `json:"created_at"`
+		Data      any       `json:"data"`
+	}
+
+	// the id is the same for every subscription, so receivers can use it to drop duplicates
+	eventID := uuid.New()
+	payload, err := json.Marshal(webhookPayload{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	return qtx.EnqueueWebhookEvent(ctx, database.EnqueueWebhookEventParams{
+		EventID:   eventID,
+		EventType: eventType,
+		Payload:   string(payload),
+		UserID:    userID,
+	})
+}
+
+// runWebhookDispatcher delivers the outbox. claiming a delivery pushes its next attempt a few minutes
+// ahead, so several instances can run it and a delivery interrupted by a crash is tried again
+func (cfg *apiConfig) runWebhookDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cfg.deliverDueWebhooks(ctx)
+		}
+	}
+}
+
+func (cfg *apiConfig) deliverDueWebhooks(ctx context.Context) {
+	// keep going while full batches come back, there may be more due deliveries
+	for {
+		deliveries, err := cfg.databaseQueries.ClaimWebhookDeliveries(ctx, webhookBatchSize)
+		if err != nil {
+			log.Printf("error claiming webhook deliveries: %v", err)
+			return
+		}
+
+		// one slow endpoint shouldn't hold up the others in the batch
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cfg.deliverWebhook(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// deliverWebhook sends one delivery and records the outcome, failures are retried with
+// exponential backoff until webhook.MaxAttempts and then dead-lettered
+func (cfg *apiConfig) deliverWebhook(ctx context.Context, delivery database.ClaimWebhookDeliveriesRow) {
+	headers, status, deliveryErr := cfg.sendWebhook(ctx, delivery)
+	if _, err := cfg.logOutboundDelivery(ctx, delivery, headers, status, deliveryErr, uuid.NullUUID{}); err != nil {
+		log.Printf("error logging webhook %v delivery: %v", delivery.ID, err)
+	}
+
+	if deliveryErr == nil {
+		if err := cfg.databaseQueries.MarkWebhookDelivered(ctx, delivery.ID); err != nil {
+			log.Printf("error marking webhook %v delivered: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	lastError := sql.NullString{String: deliveryErr.Error(), Valid: true}
+	if delivery.Attempts >= webhook.MaxAttempts {
+		log.Printf("webhook %v failed %d times, giving up: %v", delivery.ID, delivery.Attempts, deliveryErr)
+		err := cfg.databaseQueries.MarkWebhookDead(ctx, database.MarkWebhookDeadParams{
+			ID:        delivery.ID,
+			LastError: lastError,
+		})
+		if err != nil {
+			log.Printf("error marking webhook %v dead: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	err := cfg.databaseQueries.RetryWebhookDelivery(ctx, database.RetryWebhookDeliveryParams{
+		ID:            delivery.ID,
+		NextAttemptAt: time.Now().Add(webhook.RetryDelay(int(delivery.Attempts))),
+		LastError:     lastError,
+	})
+	if err != nil {
+		log.Printf("error scheduling webhook %v retry: %v", delivery.ID, err)
+	}
+}
+
+// sendWebhook posts the payload signed with the subscription's secret, any 2xx response is a success.
+// it returns the headers it sent and the response status, which is 0 when nothing came back
+func (cfg *apiConfig) sendWebhook(ctx context.Context, delivery database.ClaimWebhookDeliveriesRow) (http.Header, int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks")
+	req.Header.Set("X-Chirpy-Event", delivery.EventType)
+	req.Header.Set("X-Chirpy-Delivery", delivery.ID.String())
+	req.Header.Set(chirpySignatureHeader, webhook.Sign(delivery.Secret, time.Now(), body))
+
+	resp, err := cfg.webhookClient.Do(req)
+	if err != nil {
+		return req.Header, 0, err
+	}
+	defer resp.Body.Close()
+	// drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return req.Header, resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return req.Header, resp.StatusCode, nil
+}
+
+// logOutboundDelivery saves one attempt at sending an outbox entry in the delivery log
+func (cfg *apiConfig) logOutboundDelivery(ctx context.Context, delivery database.ClaimWebhookDeliveriesRow, headers http.Header, status int, deliveryErr error, replayOf uuid.NullUUID) (database.WebhookDelivery, error) {
+	return cfg.databaseQueries.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+		Direction:      webhookOutbound,
+		SubscriptionID: uuid.NullUUID{UUID: delivery.SubscriptionID, Valid: true},
+		OutboxID:       uuid.NullUUID{UUID: delivery.ID, Valid: true},
+		EventID:        nullString(delivery.EventID.String()),
+		EventType:      nullString(delivery.EventType),
+		Url:            nullString(delivery.Url),
+		RequestHeaders: loggedHeaders(headers),
+		RequestBody:    delivery.Payload,
+		ResponseStatus: sql.NullInt32{Int32: int32(status), Valid: status != 0},
+		Succeeded:      deliveryErr == nil,
+		Error:          nullErrorString(deliveryErr),
+		ReplayOf:       replayOf,
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// ChirpsPage is the response body of every paginated chirp listing
+type ChirpsPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// chirpCursor marks the last chirp of a page, the next page starts right after it
+type chirpCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// searchCursor marks the last search result of a page, results are ordered by rank instead of time
+type searchCursor struct {
+	Rank float32
+	ID   uuid.UUID
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursorParts joins the parts into an opaque string that clients send back as is
+func encodeCursorParts(parts ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, "|")))
+}
+
+func decodeCursorParts(cursorString string, count int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursorString)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != count {
+		return nil, errInvalidCursor
+	}
+	return parts, nil
+}
+
+func encodeCursor(cursor chirpCursor) string {
+	return encodeCursorParts(cursor.CreatedAt.Format(time.RFC3339Nano), cursor.ID.String())
+}
+
+func decodeCursor(cursorString string) (chirpCursor, error) {
+	parts, err := decodeCursorParts(cursorString, 2)
+	if err != nil {
+		return chirpCursor{}, err
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return chirpCursor{}, errInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return chirpCursor{}, errInvalidCursor
+	}
+	return chirpCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+func encodeSearchCursor(cursor searchCursor) string {
+	return encodeCursorParts(strconv.FormatFloat(float64(cursor.Rank), 'g', -1, 32), cursor.ID.String())
+}
+
+func decodeSearchCursor(cursorString string) (searchCursor, error) {
+	parts, err := decodeCursorParts(cursorString, 2)
+	if err != nil {
+		return searchCursor{}, err
+	}
+	rank, err := strconv.ParseFloat(parts[0], 32)
+	if err != nil {
+		return searchCursor{}, errInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return searchCursor{}, errInvalidCursor
+	}
+	return searchCursor{Rank: float32(rank), ID: id}, nil
+}
+
+// parseLimit reads the limit query param, an empty string means the default page size
+func parseLimit(limitString string) (int, error) {
+	if limitString == "" {
+		return defaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(limitString)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return limit, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// scopes limit what a personal access token or an oauth client can do, jwts from logging in can do everything
+const (
+	scopeChirpsWrite  = "chirps:write"
+	scopeChirpsDelete = "chirps:delete"
+	scopeProfileWrite = "profile:write"
+)
+
+var personalAccessTokenScopes = map[string]bool{
+	scopeChirpsWrite:  true,
+	scopeChirpsDelete: true,
+	scopeProfileWrite: true,
+}
+
+var errMissingScope = errors.New("token doesn't have the required scope")
+
+// authenticate returns the user of the bearer token, which is either a jwt
+// or a personal access token that has the scope. jwts issued to oauth clients also need the scope
+func (cfg *apiConfig) authenticate(r *http.Request, scope string) (uuid.UUID, error) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if !strings.HasPrefix(bearerToken, auth.PersonalAccessTokenPrefix) {
+		userID, scopes, err := cfg.jwtKeys.ValidateScopedJWT(bearerToken)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if scopes != nil && !slices.Contains(scopes, scope) {
+			return uuid.Nil, errMissingScope
+		}
+		return userID, nil
+	}
+
+	// expired tokens aren't found
+	token, err := cfg.databaseQueries.GetPersonalAccessTokenByHash(r.Context(), auth.HashToken(bearerToken))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !slices.Contains(token.Scopes, scope) {
+		return uuid.Nil, errMissingScope
+	}
+
+	if err := cfg.databaseQueries.TouchPersonalAccessToken(r.Context(), token.ID); err != nil {
+		log.Printf("error updating personal access token last use: %v", err)
+	}
+	return token.UserID, nil
+}
+
+// PersonalAccessToken is a token for bots and integrations, the token itself is only returned when it's created
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func personalAccessTokenFromDatabase(dbToken database.PersonalAccessToken) PersonalAccessToken {
+	return PersonalAccessToken{
+		ID:         dbToken.ID,
+		Name:       dbToken.Name,
+		Scopes:     dbToken.Scopes,
+		CreatedAt:  dbToken.CreatedAt,
+		ExpiresAt:  nullTimePtr(dbToken.ExpiresAt),
+		LastUsedAt: nullTimePtr(dbToken.LastUsedAt),
+	}
+}
@@ -0,0 +1,43 @@
+-- name: CreateChirpTags :exec
+INSERT INTO chirp_tags (chirp_id, tag)
+SELECT sqlc.arg('chirp_id')::uuid, UNNEST(sqlc.arg('tags')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: CreateChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+SELECT sqlc.arg('chirp_id')::uuid, users.id
+FROM users
+WHERE users.handle = ANY(sqlc.arg('handles')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpTags :exec
+DELETE FROM chirp_tags
+WHERE chirp_id = $1;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: GetChirpsByTag :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+WHERE chirp_tags.tag = sqlc.arg('tag')
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetMentions :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
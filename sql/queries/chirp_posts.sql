@@ -0,0 +1,14 @@
+-- name: RecordChirpPost :exec
+INSERT INTO chirp_posts (id, user_id, created_at)
+VALUES (gen_random_uuid(), $1, NOW());
+
+-- name: CountChirpPostsSince :one
+SELECT COUNT(*)
+FROM chirp_posts
+WHERE user_id = $1
+AND created_at > $2;
+
+-- name: DeleteChirpPostsBefore :exec
+DELETE FROM chirp_posts
+WHERE user_id = $1
+AND created_at <= $2;
@@ -1,16 +1,132 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, repost_of, publish_at)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+SELECT * FROM chirps WHERE publish_at IS NULL ORDER BY created_at ASC;
 
 -- name: GetChirp :one
-SELECT * FROM chirps WHERE id=$1 LIMIT 1;
+SELECT * FROM chirps WHERE id=$1 AND publish_at IS NULL LIMIT 1;
 
 -- name: DeleteChirp :one
 DELETE
 FROM chirps
 WHERE id = $1
 RETURNING *;
+
+-- name: GetChirpsPageAsc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND publish_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: GetChirpsPageDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND publish_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirps :many
+SELECT chirps.*, ts_rank(search_vector, websearch_to_tsquery('english', sqlc.arg('query')::text))::real AS rank
+FROM chirps
+WHERE search_vector @@ websearch_to_tsquery('english', sqlc.arg('query')::text)
+AND deleted_at IS NULL
+AND publish_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('cursor_rank')::real IS NULL
+  OR (ts_rank(search_vector, websearch_to_tsquery('english', sqlc.arg('query')::text))::real, id) < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_id')::uuid))
+ORDER BY rank DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetChirpForUpdate :one
+SELECT * FROM chirps WHERE id=$1 LIMIT 1 FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: ChirpIsReferenced :one
+SELECT EXISTS (
+  SELECT 1 FROM chirps
+  WHERE in_reply_to = sqlc.arg('id')::uuid
+  OR (repost_of = sqlc.arg('id')::uuid AND (body <> '' OR deleted_at IS NOT NULL))
+);
+
+-- name: DeletePlainRechirps :exec
+DELETE FROM chirps
+WHERE repost_of = $1
+AND body = ''
+AND deleted_at IS NULL;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: TombstoneChirp :one
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetChirpThread :many
+WITH RECURSIVE ancestors AS (
+  SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, repost_of, 0::int AS depth
+  FROM chirps
+  WHERE chirps.id = $1
+  AND chirps.publish_at IS NULL
+  UNION ALL
+  SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.deleted_at, c.repost_of, (a.depth - 1)::int
+  FROM chirps c
+  JOIN ancestors a ON c.id = a.in_reply_to
+  WHERE c.publish_at IS NULL
+), descendants AS (
+  SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, repost_of, 0::int AS depth
+  FROM chirps
+  WHERE chirps.id = $1
+  AND chirps.publish_at IS NULL
+  UNION ALL
+  SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.deleted_at, c.repost_of, (d.depth + 1)::int
+  FROM chirps c
+  JOIN descendants d ON c.in_reply_to = d.id
+  WHERE c.publish_at IS NULL
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, repost_of, depth FROM ancestors WHERE depth < 0
+UNION ALL
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, repost_of, depth FROM descendants
+ORDER BY depth ASC, created_at ASC, id ASC;
+
+-- name: GetScheduledChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1
+AND publish_at IS NOT NULL
+ORDER BY publish_at ASC;
+
+-- name: CancelScheduledChirp :one
+DELETE FROM chirps
+WHERE id = $1
+AND user_id = $2
+AND publish_at IS NOT NULL
+RETURNING *;
+
+-- name: PublishDueChirps :many
+UPDATE chirps
+SET publish_at = NULL, created_at = NOW(), updated_at = NOW()
+WHERE id IN (
+  SELECT id FROM chirps
+  WHERE publish_at <= NOW()
+  ORDER BY publish_at
+  LIMIT sqlc.arg('batch_size')
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
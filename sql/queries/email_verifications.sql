@@ -0,0 +1,25 @@
+-- name: CreateEmailVerification :exec
+INSERT INTO email_verifications (token_hash, user_id, email, created_at, expires_at)
+VALUES ($1, $2, $3, NOW(), $4);
+
+-- name: UseEmailVerification :one
+UPDATE email_verifications
+SET used_at = NOW()
+WHERE token_hash = $1
+AND used_at IS NULL
+AND expires_at > NOW()
+RETURNING *;
+
+-- name: ExpireEmailVerifications :exec
+UPDATE email_verifications
+SET used_at = NOW()
+WHERE user_id = $1
+AND used_at IS NULL;
+
+-- name: GetLatestEmailVerification :one
+SELECT *
+FROM email_verifications
+WHERE user_id = $1
+AND used_at IS NULL
+ORDER BY created_at DESC
+LIMIT 1;
@@ -0,0 +1,17 @@
+-- name: LikeChirp :exec
+INSERT INTO likes (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: UnlikeChirp :execrows
+DELETE FROM likes
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: GetLikeStats :many
+SELECT chirp_id,
+  COUNT(*)::int AS like_count,
+  COALESCE(BOOL_OR(user_id = sqlc.narg('viewer_id')::uuid), FALSE)::bool AS liked_by_me
+FROM likes
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_id;
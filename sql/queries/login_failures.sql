@@ -0,0 +1,31 @@
+-- name: GetLoginLockouts :many
+SELECT *
+FROM login_failures
+WHERE key = ANY(sqlc.arg('keys')::text[])
+AND locked_until > NOW();
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (key, failures, last_failed_at)
+VALUES (sqlc.arg('key'), 1, NOW())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+    WHEN login_failures.last_failed_at < sqlc.arg('reset_before')::timestamp THEN 1
+    ELSE login_failures.failures + 1
+  END,
+  last_failed_at = NOW()
+RETURNING *;
+
+-- name: LockLogin :exec
+UPDATE login_failures
+SET locked_until = $1
+WHERE key = $2;
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_failures
+WHERE key = $1;
+
+-- name: GetActiveLoginLockouts :many
+SELECT *
+FROM login_failures
+WHERE locked_until > NOW()
+ORDER BY locked_until DESC;
@@ -0,0 +1,32 @@
+-- name: CreateMediaFile :one
+INSERT INTO media_files (id, user_id, sha256, content_type, size_bytes, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, NOW())
+RETURNING *;
+
+-- name: GetMediaFileBySHA256 :one
+SELECT *
+FROM media_files
+WHERE sha256 = $1
+LIMIT 1;
+
+-- name: GetOwnedMediaFiles :many
+SELECT *
+FROM media_files
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+AND user_id = sqlc.arg('user_id');
+
+-- name: AttachMediaToChirp :exec
+INSERT INTO chirp_attachments (chirp_id, media_id, position)
+SELECT sqlc.arg('chirp_id')::uuid, attached.media_id, attached.ord::int
+FROM UNNEST(sqlc.arg('media_ids')::uuid[]) WITH ORDINALITY AS attached(media_id, ord);
+
+-- name: DeleteChirpAttachments :exec
+DELETE FROM chirp_attachments
+WHERE chirp_id = $1;
+
+-- name: GetChirpAttachments :many
+SELECT chirp_attachments.chirp_id, media_files.*
+FROM chirp_attachments
+JOIN media_files ON media_files.id = chirp_attachments.media_id
+WHERE chirp_attachments.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_attachments.chirp_id, chirp_attachments.position;
@@ -0,0 +1,63 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (id, user_id, url, secret, events, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, NOW())
+RETURNING *;
+
+-- name: GetWebhookSubscriptions :many
+SELECT *
+FROM webhook_subscriptions
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1
+AND user_id = $2;
+
+-- name: EnqueueWebhookEvent :exec
+INSERT INTO webhook_outbox (id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+SELECT gen_random_uuid(), webhook_subscriptions.id, sqlc.arg('event_id'), sqlc.arg('event_type')::text, sqlc.arg('payload'), 'pending', 0, NOW(), NOW()
+FROM webhook_subscriptions
+WHERE webhook_subscriptions.user_id = sqlc.arg('user_id')
+AND sqlc.arg('event_type')::text = ANY(webhook_subscriptions.events);
+
+-- name: ClaimWebhookDeliveries :many
+WITH claimed AS (
+  UPDATE webhook_outbox
+  SET attempts = attempts + 1, next_attempt_at = NOW() + INTERVAL '5 minutes'
+  WHERE webhook_outbox.id IN (
+    SELECT id
+    FROM webhook_outbox
+    WHERE status = 'pending'
+    AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+  )
+  RETURNING webhook_outbox.id, webhook_outbox.subscription_id, webhook_outbox.event_id, webhook_outbox.event_type, webhook_outbox.payload, webhook_outbox.attempts
+)
+SELECT claimed.id, claimed.subscription_id, claimed.event_id, claimed.event_type, claimed.payload, claimed.attempts, webhook_subscriptions.url, webhook_subscriptions.secret
+FROM claimed
+JOIN webhook_subscriptions ON webhook_subscriptions.id = claimed.subscription_id;
+
+-- name: GetWebhookOutboxDelivery :one
+SELECT webhook_outbox.id, webhook_outbox.subscription_id, webhook_outbox.event_id, webhook_outbox.event_type, webhook_outbox.payload, webhook_outbox.attempts, webhook_subscriptions.url, webhook_subscriptions.secret
+FROM webhook_outbox
+JOIN webhook_subscriptions ON webhook_subscriptions.id = webhook_outbox.subscription_id
+WHERE webhook_outbox.id = $1
+LIMIT 1;
+
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_outbox
+SET status = 'delivered', delivered_at = NOW(), last_error = NULL
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_outbox
+SET next_attempt_at = $2, last_error = $3
+WHERE id = $1;
+
+-- name: MarkWebhookDead :exec
+UPDATE webhook_outbox
+SET status = 'dead', last_error = $2
+WHERE id = $1;
@@ -0,0 +1,76 @@
+-- name: ActivateSubscription :one
+INSERT INTO subscriptions (user_id, plan, status, current_period_end, created_at, updated_at)
+VALUES ($1, $2, 'active', $3, NOW(), NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+    status = 'active',
+    current_period_end = EXCLUDED.current_period_end,
+    grace_period_end = NULL,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: RenewSubscription :execrows
+UPDATE subscriptions
+SET plan = $2,
+    status = 'active',
+    current_period_end = $3,
+    grace_period_end = NULL,
+    updated_at = NOW()
+WHERE user_id = $1
+AND status <> 'expired';
+
+-- name: CancelSubscription :execrows
+UPDATE subscriptions
+SET status = 'cancelled', grace_period_end = NULL, updated_at = NOW()
+WHERE user_id = $1
+AND status IN ('active', 'past_due');
+
+-- name: FailSubscriptionPayment :execrows
+UPDATE subscriptions
+SET status = 'past_due',
+    grace_period_end = COALESCE(grace_period_end, GREATEST(current_period_end, NOW()) + INTERVAL '7 days'),
+    updated_at = NOW()
+WHERE user_id = $1
+AND status IN ('active', 'past_due');
+
+-- name: ExpireSubscription :execrows
+UPDATE subscriptions
+SET status = 'expired',
+    current_period_end = LEAST(current_period_end, NOW()),
+    grace_period_end = NULL,
+    updated_at = NOW()
+WHERE user_id = $1
+AND status <> 'expired';
+
+-- name: GetSubscription :one
+SELECT *
+FROM subscriptions
+WHERE user_id = $1
+LIMIT 1;
+
+-- name: SyncChirpyRed :exec
+UPDATE users
+SET is_chirpy_red = EXISTS (
+  SELECT 1
+  FROM subscriptions
+  WHERE subscriptions.user_id = users.id
+  AND (
+    (subscriptions.status IN ('active', 'cancelled') AND subscriptions.current_period_end > NOW())
+    OR (subscriptions.status = 'past_due' AND subscriptions.grace_period_end > NOW())
+  )
+)
+WHERE id = $1;
+
+-- name: ExpireLapsedSubscriptions :many
+WITH lapsed AS (
+  UPDATE subscriptions
+  SET status = 'expired', grace_period_end = NULL, updated_at = NOW()
+  WHERE (status IN ('active', 'cancelled') AND current_period_end <= NOW())
+  OR (status = 'past_due' AND grace_period_end <= NOW())
+  RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = FALSE
+FROM lapsed
+WHERE users.id = lapsed.user_id
+RETURNING users.id;
@@ -0,0 +1,78 @@
+-- name: SetTOTPSecret :execrows
+UPDATE users
+SET totp_secret = $1, updated_at = NOW()
+WHERE id = $2
+AND totp_enabled_at IS NULL;
+
+-- name: EnableTOTP :execrows
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = $1, updated_at = NOW()
+WHERE id = $2
+AND totp_secret IS NOT NULL
+AND totp_enabled_at IS NULL;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $1
+WHERE id = $2
+AND totp_last_step < $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (id, user_id, code_hash, created_at)
+SELECT gen_random_uuid(), sqlc.arg('user_id')::uuid, code_hash, NOW()
+FROM UNNEST(sqlc.arg('code_hashes')::text[]) AS code_hash;
+
+-- name: GetUnusedRecoveryCodes :many
+SELECT *
+FROM recovery_codes
+WHERE user_id = $1
+AND used_at IS NULL;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE id = $1
+AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateLoginChallenge :exec
+INSERT INTO login_challenges (token, user_id, created_at, expires_at)
+VALUES ($1, $2, NOW(), $3);
+
+-- name: GetLoginChallenge :one
+SELECT *
+FROM login_challenges
+WHERE token = $1
+AND expires_at > NOW()
+LIMIT 1;
+
+-- name: DeleteExcessLoginChallenges :exec
+DELETE FROM login_challenges
+WHERE user_id = sqlc.arg('user_id')
+AND token NOT IN (
+  SELECT token
+  FROM login_challenges
+  WHERE user_id = sqlc.arg('user_id')
+  ORDER BY created_at DESC
+  LIMIT sqlc.arg('keep')
+);
+
+-- name: AttemptLoginChallenge :one
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE token = $1
+AND expires_at > NOW()
+AND attempts < $2
+RETURNING *;
+
+-- name: DeleteLoginChallenge :execrows
+DELETE FROM login_challenges
+WHERE token = $1;
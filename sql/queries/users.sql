@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
 RETURNING *;
 
 -- name: AuthenticateUser :one
@@ -15,8 +15,31 @@ SET email = $1, hashed_password = $2, updated_at = NOW()
 WHERE id = $3
 RETURNING *;
 
--- name: UpgradeUser :one
-UPDATE users
-SET is_chirpy_red = TRUE
+-- name: GetUserByID :one
+SELECT *
+FROM users
 WHERE id = $1
+LIMIT 1;
+
+-- name: SetUserHandle :one
+UPDATE users
+SET handle = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET hashed_password = $1, updated_at = NOW()
+WHERE id = $2;
+
+-- name: RehashUserPassword :exec
+UPDATE users
+SET hashed_password = sqlc.arg('new_hash')
+WHERE id = sqlc.arg('id')
+AND hashed_password = sqlc.arg('old_hash');
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email = $1, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $2
 RETURNING *;
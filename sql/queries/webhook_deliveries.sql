@@ -0,0 +1,29 @@
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+  id, direction, provider, subscription_id, outbox_id, event_id, event_type, url,
+  request_headers, request_body, response_status, succeeded, error, signature_verified, replay_of, created_at
+)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW())
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT *
+FROM webhook_deliveries
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetWebhookDeliveries :many
+SELECT *
+FROM webhook_deliveries
+WHERE (sqlc.narg('direction')::text IS NULL OR direction = sqlc.narg('direction'))
+AND (sqlc.narg('provider')::text IS NULL OR provider = sqlc.narg('provider'))
+AND (sqlc.narg('event_type')::text IS NULL OR event_type = sqlc.narg('event_type'))
+AND (sqlc.narg('succeeded')::boolean IS NULL OR succeeded = sqlc.narg('succeeded'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+  OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteWebhookDeliveriesBefore :execrows
+DELETE FROM webhook_deliveries
+WHERE created_at < $1;
@@ -0,0 +1,4 @@
+-- name: RecordWebhookEvent :execrows
+INSERT INTO webhook_events (provider, event_id, event_type, received_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (provider, event_id) DO NOTHING;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chirps
+ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX chirps_search_vector_idx ON chirps USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX chirps_search_vector_idx;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE chirps
+DROP COLUMN search_vector;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chirps
+ADD COLUMN in_reply_to UUID REFERENCES chirps(id) ON DELETE SET NULL,
+ADD COLUMN deleted_at TIMESTAMP DEFAULT NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX chirps_in_reply_to_idx ON chirps (in_reply_to);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chirps
+DROP COLUMN in_reply_to,
+DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE follows (
+  follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (follower_id, followee_id),
+  CHECK (follower_id <> followee_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX follows_followee_id_idx ON follows (followee_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX chirps_user_id_created_at_id_idx ON chirps (user_id, created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX chirps_user_id_created_at_id_idx;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE follows;
+-- +goose StatementEnd
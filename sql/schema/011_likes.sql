@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE likes (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  UNIQUE (user_id, chirp_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX likes_chirp_id_idx ON likes (chirp_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE likes;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chirps
+ADD COLUMN repost_of UUID REFERENCES chirps(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX chirps_repost_of_idx ON chirps (repost_of);
+-- +goose StatementEnd
+
+-- a user can rechirp a chirp only once, quotes have a body and aren't limited
+-- +goose StatementBegin
+CREATE UNIQUE INDEX chirps_plain_rechirp_idx ON chirps (user_id, repost_of)
+WHERE body = '' AND repost_of IS NOT NULL AND deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chirps
+DROP COLUMN repost_of;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN handle TEXT UNIQUE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE chirp_tags (
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  tag TEXT NOT NULL,
+  PRIMARY KEY (chirp_id, tag)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX chirp_tags_tag_idx ON chirp_tags (tag);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE chirp_mentions (
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  PRIMARY KEY (chirp_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE chirp_mentions;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE chirp_tags;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users
+DROP COLUMN handle;
+-- +goose StatementEnd
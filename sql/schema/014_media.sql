@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE media_files (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  sha256 TEXT NOT NULL,
+  content_type TEXT NOT NULL,
+  size_bytes BIGINT NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX media_files_sha256_idx ON media_files (sha256);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE chirp_attachments (
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  media_id UUID NOT NULL REFERENCES media_files(id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  PRIMARY KEY (chirp_id, position),
+  UNIQUE (chirp_id, media_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE chirp_attachments;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE media_files;
+-- +goose StatementEnd
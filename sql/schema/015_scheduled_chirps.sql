@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chirps
+ADD COLUMN publish_at TIMESTAMP DEFAULT NULL;
+-- +goose StatementEnd
+
+-- only pending chirps have a publish_at, published ones have it cleared
+-- +goose StatementBegin
+CREATE INDEX chirps_publish_at_idx ON chirps (publish_at) WHERE publish_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chirps
+DROP COLUMN publish_at;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- failed logins are counted per email and per ip address, keys are prefixed with email: or ip:
+CREATE TABLE login_failures (
+  key TEXT PRIMARY KEY,
+  failures INT NOT NULL,
+  last_failed_at TIMESTAMP NOT NULL,
+  locked_until TIMESTAMP
+);
+
+CREATE INDEX login_failures_locked_until_idx ON login_failures (locked_until)
+WHERE locked_until IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE login_failures;
+-- +goose StatementEnd
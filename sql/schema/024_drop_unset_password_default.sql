@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- new users always get a real hash, accounts that still have 'unset' can only log in after resetting their password
+ALTER TABLE users
+ALTER COLUMN hashed_password DROP DEFAULT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+ALTER COLUMN hashed_password SET DEFAULT 'unset';
+-- +goose StatementEnd
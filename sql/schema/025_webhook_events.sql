@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- events from webhook providers that were already applied, so retried deliveries are only acknowledged
+CREATE TABLE webhook_events (
+  provider TEXT NOT NULL,
+  event_id TEXT NOT NULL,
+  event_type TEXT NOT NULL,
+  received_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (provider, event_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webhook_events;
+-- +goose StatementEnd
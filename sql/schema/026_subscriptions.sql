@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a user's chirpy red subscription, kept up to date by polka's webhooks.
+-- users.is_chirpy_red is derived from it and only changed together with it
+CREATE TABLE subscriptions (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  plan TEXT NOT NULL,
+  -- active, cancelled (runs until current_period_end), past_due (runs until grace_period_end) or expired
+  status TEXT NOT NULL,
+  current_period_end TIMESTAMP NOT NULL,
+  grace_period_end TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX subscriptions_status_idx ON subscriptions (status);
+
+-- users upgraded before subscriptions existed get a period, polka renews it from now on
+INSERT INTO subscriptions (user_id, plan, status, current_period_end, created_at, updated_at)
+SELECT id, 'red', 'active', NOW() + INTERVAL '30 days', NOW(), NOW()
+FROM users
+WHERE is_chirpy_red;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE subscriptions;
+-- +goose StatementEnd
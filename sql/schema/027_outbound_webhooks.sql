@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+-- endpoints integrators registered for events about their own account
+CREATE TABLE webhook_subscriptions (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  -- kept in plain text because every delivery is signed with it
+  secret TEXT NOT NULL,
+  events TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX webhook_subscriptions_user_id_idx ON webhook_subscriptions (user_id);
+
+-- one row per event and subscription, written in the same transaction as the change it describes
+CREATE TABLE webhook_outbox (
+  id UUID PRIMARY KEY,
+  subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+  event_id UUID NOT NULL,
+  event_type TEXT NOT NULL,
+  -- text instead of jsonb so the signed bytes are sent unchanged
+  payload TEXT NOT NULL,
+  -- pending, delivered or dead once every attempt failed
+  status TEXT NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL,
+  last_error TEXT,
+  created_at TIMESTAMP NOT NULL,
+  delivered_at TIMESTAMP
+);
+
+CREATE INDEX webhook_outbox_pending_idx ON webhook_outbox (next_attempt_at) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webhook_outbox;
+DROP TABLE webhook_subscriptions;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- every webhook request chirpy received or sent, with what came of it
+CREATE TABLE webhook_deliveries (
+  id UUID PRIMARY KEY,
+  -- inbound from a provider like polka, or outbound to a subscription
+  direction TEXT NOT NULL,
+  provider TEXT,
+  subscription_id UUID REFERENCES webhook_subscriptions(id) ON DELETE SET NULL,
+  outbox_id UUID REFERENCES webhook_outbox(id) ON DELETE SET NULL,
+  event_id TEXT,
+  event_type TEXT,
+  url TEXT,
+  -- a json object of header names to values, credentials are left out
+  request_headers TEXT NOT NULL,
+  request_body TEXT NOT NULL,
+  -- the status chirpy answered with for inbound requests, the one it got back for outbound ones
+  response_status INTEGER,
+  succeeded BOOLEAN NOT NULL,
+  error TEXT,
+  -- inbound requests without a valid signature are logged with a truncated body and can't be replayed
+  signature_verified BOOLEAN NOT NULL,
+  replay_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_created_at_id_idx ON webhook_deliveries (created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webhook_deliveries;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- one row per chirp posted, kept when the chirp is deleted so deleting doesn't reset the hourly limit
+CREATE TABLE chirp_posts (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX chirp_posts_user_id_created_at_idx ON chirp_posts (user_id, created_at);
+
+-- chirps from the last hour still count after the upgrade
+INSERT INTO chirp_posts (id, user_id, created_at)
+SELECT gen_random_uuid(), user_id, created_at
+FROM chirps
+WHERE created_at > NOW() - INTERVAL '1 hour';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE chirp_posts;
+-- +goose StatementEnd
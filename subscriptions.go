@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// the polka events that change a subscription. a failed payment keeps chirpy red for a 7 day
+// grace period (set in FailSubscriptionPayment), a cancelled subscription runs until its period ends
+const (
+	eventUserUpgraded          = "user.upgraded"
+	eventUserDowngraded        = "user.downgraded"
+	eventSubscriptionRenewed   = "subscription.renewed"
+	eventSubscriptionCancelled = "subscription.cancelled"
+	eventPaymentFailed         = "payment.failed"
+)
+
+const (
+	planChirpyRed = "red"
+	// used when polka doesn't send the end of the paid period
+	defaultSubscriptionPeriod = 30 * 24 * time.Hour
+)
+
+var subscriptionEvents = map[string]bool{
+	eventUserUpgraded:          true,
+	eventUserDowngraded:        true,
+	eventSubscriptionRenewed:   true,
+	eventSubscriptionCancelled: true,
+	eventPaymentFailed:         true,
+}
+
+var errSubscriptionUserNotFound = errors.New("subscription user not found")
+
+// SubscriptionEventData is the data of polka's subscription events
+type SubscriptionEventData struct {
+	UserID           uuid.UUID  `json:"user_id"`
+	Plan             string     `json:"plan"`
+	CurrentPeriodEnd *time.Time `json:"current_period_end"`
+}
+
+// applySubscriptionEvent changes the user's subscription and then derives is_chirpy_red from it,
+// qtx has to be a transaction so both change together
+func applySubscriptionEvent(ctx context.Context, qtx *database.Queries, event string, data SubscriptionEventData) error {
+	if _, err := qtx.GetUserByID(ctx, data.UserID); err == sql.ErrNoRows {
+		return errSubscriptionUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	// events for a subscription in another state, like a renewal after it expired, change nothing.
+	// an upgrade starts a new subscription whatever happened to the old one
+	plan := data.Plan
+	if plan == "" {
+		plan = planChirpyRed
+	}
+	currentPeriodEnd := time.Now().Add(defaultSubscriptionPeriod)
+	if data.CurrentPeriodEnd != nil {
+		// stored in local time like time.Now(), the column has no time zone
+		currentPeriodEnd = data.CurrentPeriodEnd.Local()
+	}
+
+	var err error
+	switch event {
+	case eventUserUpgraded:
+		_, err = qtx.ActivateSubscription(ctx, database.ActivateSubscriptionParams{
+			UserID:           data.UserID,
+			Plan:             plan,
+			CurrentPeriodEnd: currentPeriodEnd,
+		})
+	case eventSubscriptionRenewed:
+		_, err = qtx.RenewSubscription(ctx, database.RenewSubscriptionParams{
+			UserID:           data.UserID,
+			Plan:             plan,
+			CurrentPeriodEnd: currentPeriodEnd,
+		})
+	case eventSubscriptionCancelled:
+		_, err = qtx.CancelSubscription(ctx, data.UserID)
+	case eventPaymentFailed:
+		_, err = qtx.FailSubscriptionPayment(ctx, data.UserID)
+	case eventUserDowngraded:
+		_, err = qtx.ExpireSubscription(ctx, data.UserID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return qtx.SyncChirpyRed(ctx, data.UserID)
+}
+
+// runSubscriptionExpirer expires subscriptions whose period or grace period is over
+func (cfg *apiConfig) runSubscriptionExpirer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := cfg.databaseQueries.ExpireLapsedSubscriptions(ctx)
+			if err != nil {
+				log.Printf("error expiring subscriptions: %v", err)
+				continue
+			}
+			if len(expired) > 0 {
+				log.Printf("expired %d chirpy red subscriptions", len(expired))
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/auth"
+	"github.com/troclaux/chirpy/internal/database"
+	"github.com/troclaux/chirpy/internal/totp"
+)
+
+const (
+	totpIssuer        = "Chirpy"
+	recoveryCodeCount = 10
+	// a challenge is only good for a few guesses, after that the user has to log in again
+	loginChallengeDuration    = 5 * time.Minute
+	maxLoginChallengeAttempts = 5
+	// older challenges are deleted when a user has more open, so logging in again doesn't add guesses
+	maxOpenLoginChallenges = 3
+)
+
+// SecondFactor is the code sent to complete a login or change two-factor authentication,
+// either a totp code from the authenticator app or one of the recovery codes
+type SecondFactor struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// checkSecondFactor validates the code and marks it as used so it can't be replayed
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, user database.User, factor SecondFactor) (bool, error) {
+	if factor.Code != "" {
+		step, ok := totp.Validate(user.TotpSecret.String, factor.Code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		// only steps after the last accepted one are valid
+		used, err := cfg.databaseQueries.UseTOTPStep(ctx, database.UseTOTPStepParams{
+			TotpLastStep: step,
+			ID:           user.ID,
+		})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+
+	if factor.RecoveryCode != "" {
+		recoveryCodes, err := cfg.databaseQueries.GetUnusedRecoveryCodes(ctx, user.ID)
+		if err != nil {
+			return false, err
+		}
+		recoveryCode := auth.NormalizeRecoveryCode(factor.RecoveryCode)
+		for _, storedCode := range recoveryCodes {
+			if !auth.CheckRecoveryCode(recoveryCode, storedCode.CodeHash) {
+				continue
+			}
+			used, err := cfg.databaseQueries.UseRecoveryCode(ctx, storedCode.ID)
+			if err != nil {
+				return false, err
+			}
+			return used == 1, nil
+		}
+	}
+
+	return false, nil
+}
+
+// makeRecoveryCodes returns new recovery codes and the hashes that are stored in their place
+func makeRecoveryCodes() ([]string, []string, error) {
+	codes := []string{}
+	hashes := []string{}
+	for range recoveryCodeCount {
+		code, err := auth.MakeRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		hash, err := auth.HashRecoveryCode(auth.NormalizeRecoveryCode(code))
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
+
+// writeLoginChallenge responds to a correct password when the user has two-factor authentication on
+func (cfg *apiConfig) writeLoginChallenge(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	challengeToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("error generating login challenge: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = cfg.databaseQueries.CreateLoginChallenge(r.Context(), database.CreateLoginChallengeParams{
+		Token:     challengeToken,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(loginChallengeDuration),
+	})
+	if err != nil {
+		log.Printf("error creating login challenge: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = cfg.databaseQueries.DeleteExcessLoginChallenges(r.Context(), database.DeleteExcessLoginChallengesParams{
+		UserID: userID,
+		Keep:   maxOpenLoginChallenges,
+	})
+	if err != nil {
+		log.Printf("error deleting old login challenges: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	type LoginChallenge struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(LoginChallenge{TwoFactorRequired: true, ChallengeToken: challengeToken}); err != nil {
+		log.Printf("error encoding response: %v", err)
+		return
+	}
+}
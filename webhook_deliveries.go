@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/troclaux/chirpy/internal/database"
+)
+
+// every webhook request chirpy receives or sends is saved in the delivery log
+const (
+	webhookInbound  = "inbound"
+	webhookOutbound = "outbound"
+)
+
+// deliveries older than this are deleted, the log is for debugging recent failures and
+// unsigned requests can't grow it forever
+const webhookDeliveryRetention = 30 * 24 * time.Hour
+
+// headers that carry credentials are left out of the delivery log
+var redactedWebhookHeaders = []string{"Authorization", "Cookie"}
+
+// WebhookDelivery is one logged webhook request and its outcome
+type WebhookDelivery struct {
+	ID                uuid.UUID       `json:"id"`
+	Direction         string          `json:"direction"`
+	Provider          string          `json:"provider,omitempty"`
+	SubscriptionID    *uuid.UUID      `json:"subscription_id,omitempty"`
+	OutboxID          *uuid.UUID      `json:"outbox_id,omitempty"`
+	EventID           string          `json:"event_id,omitempty"`
+	EventType         string          `json:"event_type,omitempty"`
+	URL               string          `json:"url,omitempty"`
+	RequestHeaders    json.RawMessage `json:"request_headers"`
+	RequestBody       string          `json:"request_body"`
+	ResponseStatus    *int            `json:"response_status"`
+	Succeeded         bool            `json:"succeeded"`
+	Error             string          `json:"error,omitempty"`
+	SignatureVerified bool            `json:"signature_verified"`
+	ReplayOf          *uuid.UUID      `json:"replay_of,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+func webhookDeliveryFromDatabase(dbDelivery database.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:                dbDelivery.ID,
+		Direction:         dbDelivery.Direction,
+		Provider:          dbDelivery.Provider.String,
+		EventID:           dbDelivery.EventID.String,
+		EventType:         dbDelivery.EventType.String,
+		URL:               dbDelivery.Url.String,
+		RequestHeaders:    json.RawMessage(dbDelivery.RequestHeaders),
+		RequestBody:       dbDelivery.RequestBody,
+		Succeeded:         dbDelivery.Succeeded,
+		Error:             dbDelivery.Error.String,
+		SignatureVerified: dbDelivery.SignatureVerified,
+		CreatedAt:         dbDelivery.CreatedAt,
+	}
+	if dbDelivery.SubscriptionID.Valid {
+		delivery.SubscriptionID = &dbDelivery.SubscriptionID.UUID
+	}
+	if dbDelivery.OutboxID.Valid {
+		delivery.OutboxID = &dbDelivery.OutboxID.UUID
+	}
+	if dbDelivery.ResponseStatus.Valid {
+		status := int(dbDelivery.ResponseStatus.Int32)
+		delivery.ResponseStatus = &status
+	}
+	if dbDelivery.ReplayOf.Valid {
+		delivery.ReplayOf = &dbDelivery.ReplayOf.UUID
+	}
+	return delivery
+}
+
+// loggedHeaders encodes the headers as json for the delivery log, without credentials
+func loggedHeaders(headers http.Header) string {
+	logged := headers.Clone()
+	if logged == nil {
+		logged = http.Header{}
+	}
+	for _, header := range redactedWebhookHeaders {
+		logged.Del(header)
+	}
+	encoded, err := json.Marshal(logged)
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func nullErrorString(err error) sql.NullString {
+	if err == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: err.Error(), Valid: true}
+}
+
+// runWebhookDeliveryPruner deletes logged deliveries once they're past the retention period
+func (cfg *apiConfig) runWebhookDeliveryPruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := cfg.databaseQueries.DeleteWebhookDeliveriesBefore(ctx, time.Now().Add(-webhookDeliveryRetention))
+			if err != nil {
+				log.Printf("error pruning webhook deliveries: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("pruned %d webhook deliveries", deleted)
+			}
+		}
+	}
+}